@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strconv"
+	"time"
+)
+
+const (
+	snoozeDuration = time.Hour
+)
+
+var (
+	ackButton    = tele.Btn{Unique: "ack"}
+	snoozeButton = tele.Btn{Unique: "snooze"}
+	muteButton   = tele.Btn{Unique: "mute"}
+)
+
+// incidentMarkup
+// Buttons of the down alert, alert without incident has none
+func incidentMarkup(incidentId int64) *tele.ReplyMarkup {
+	if incidentId == 0 {
+		return nil
+	}
+
+	markup := &tele.ReplyMarkup{}
+	data := strconv.FormatInt(incidentId, 10)
+	markup.Inline(markup.Row(
+		markup.Data("Acknowledge", ackButton.Unique, data),
+		markup.Data("Snooze 1h", snoozeButton.Unique, data),
+		markup.Data("Mute", muteButton.Unique, data),
+	))
+
+	return markup
+}
+
+// acknowledgeIncident
+// Acknowledged incident gets no more reminders or escalations,
+// every alert of the incident shows who is on it
+func acknowledgeIncident(c tele.Context) error {
+	ctx := context.Background()
+	incidentId, found := chatIncident(ctx, c)
+	if !found {
+		return c.Respond(&tele.CallbackResponse{Text: "unknown incident"})
+	}
+
+	ackedBy := senderName(c.Sender())
+	ackedAt := time.Now()
+	acked, err := botStorage.q.AcknowledgeIncident(ctx, monitor_db.AcknowledgeIncidentParams{
+		Ackedby:   ackedBy,
+		Ackedbyid: c.Sender().ID,
+		Ackedat:   sql.NullTime{Time: ackedAt, Valid: true},
+		ID:        incidentId,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("acknowledge incident")
+		return c.Respond(&tele.CallbackResponse{Text: "Internal error"})
+	}
+	if acked == 0 {
+		return c.Respond(&tele.CallbackResponse{Text: "incident is already acknowledged or resolved"})
+	}
+
+	log.Info().Int64("incident", incidentId).Str("by", ackedBy).Msg("incident is acknowledged")
+	markAcknowledged(ctx, c.Bot(), incidentId, c.Message(), ackedBy, ackedAt)
+
+	return c.Respond(&tele.CallbackResponse{Text: "acknowledged"})
+}
+
+func snoozeIncident(c tele.Context) error {
+	ctx := context.Background()
+	incidentId, found := chatIncident(ctx, c)
+	if !found {
+		return c.Respond(&tele.CallbackResponse{Text: "unknown incident"})
+	}
+
+	snoozedUntil := time.Now().Add(snoozeDuration)
+	_, err := botStorage.q.SnoozeIncidentMessage(ctx, monitor_db.SnoozeIncidentMessageParams{
+		Snoozeduntil: sql.NullTime{Time: snoozedUntil, Valid: true},
+		Incidentid:   incidentId,
+		Chatid:       c.Chat().ID,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("snooze incident")
+		return c.Respond(&tele.CallbackResponse{Text: "Internal error"})
+	}
+
+	return c.Respond(&tele.CallbackResponse{
+		Text: fmt.Sprintf("snoozed until %s UTC", snoozedUntil.UTC().Format("15:04")),
+	})
+}
+
+// muteIncident
+// Muted incident is not repeated in the chat,
+// recovery is still sent
+func muteIncident(c tele.Context) error {
+	ctx := context.Background()
+	incidentId, found := chatIncident(ctx, c)
+	if !found {
+		return c.Respond(&tele.CallbackResponse{Text: "unknown incident"})
+	}
+
+	_, err := botStorage.q.MuteIncidentMessage(ctx, monitor_db.MuteIncidentMessageParams{
+		Incidentid: incidentId,
+		Chatid:     c.Chat().ID,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("mute incident")
+		return c.Respond(&tele.CallbackResponse{Text: "Internal error"})
+	}
+
+	return c.Respond(&tele.CallbackResponse{Text: "muted until recovery"})
+}
+
+// chatIncident
+// Callback data can be forged, so the incident of the button
+// must have been sent to the chat the button is pressed in
+func chatIncident(ctx context.Context, c tele.Context) (int64, bool) {
+	incidentId, err := strconv.ParseInt(c.Data(), 10, 64)
+	if err != nil || c.Chat() == nil {
+		return 0, false
+	}
+
+	messages, err := botStorage.q.CountChatIncidentMessages(ctx, monitor_db.CountChatIncidentMessagesParams{
+		Incidentid: incidentId,
+		Chatid:     c.Chat().ID,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("check incident chat")
+		return 0, false
+	}
+
+	return incidentId, messages > 0
+}
+
+// markAcknowledged
+// Every message of the incident is edited with its own content,
+// alerts, escalations and flapping summaries lose their buttons.
+// Messages recorded without content get the content of the clicked alert
+func markAcknowledged(ctx context.Context, bot *tele.Bot, incidentId int64, alert *tele.Message, ackedBy string, ackedAt time.Time) {
+	messages, err := botStorage.q.GetIncidentMessages(ctx, incidentId)
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("load incident messages")
+		return
+	}
+
+	alertContent, alertIsDocument := messageContent(alert)
+	for _, message := range messages {
+		content, isDocument := message.Content, message.Document
+		if len(content) == 0 {
+			content, isDocument = alertContent, alertIsDocument
+		}
+		content = acknowledgedContent(content, ackedBy, ackedAt, isDocument)
+
+		stored := tele.StoredMessage{
+			MessageID: strconv.FormatInt(message.Messageid, 10),
+			ChatID:    message.Chatid,
+		}
+		if isDocument {
+			_, err = bot.EditCaption(stored, content)
+		} else {
+			_, err = bot.Edit(stored, content)
+		}
+		if err != nil {
+			log.Error().Int64("chat", message.Chatid).Err(err).Msg("mark alert acknowledged")
+		}
+	}
+}
+
+func acknowledgedContent(content, ackedBy string, ackedAt time.Time, isCaption bool) string {
+	acked := fmt.Sprintf("\n\n👀 acked by %s at %s UTC", ackedBy, ackedAt.UTC().Format("15:04"))
+	if isCaption && len(content)+len(acked) > telegramCaptionLength {
+		content = truncate(content, telegramCaptionLength-len(acked)-len("..."))
+	}
+
+	return content + acked
+}
+
+func senderName(user *tele.User) string {
+	if len(user.Username) > 0 {
+		return "@" + user.Username
+	}
+
+	return user.FirstName
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcknowledgedIncidentStopsReminders(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+	subscribe(t, 2, endpoint)
+
+	alertedAt := time.Now().Add(-time.Hour)
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+		DownSince:       alertedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, clientId := range []int64{1, 2} {
+		err := botStorage.q.SetReminder(ctx, monitor_db.SetReminderParams{
+			Reminderinterval: int64(30 * time.Minute / time.Second),
+			Maxreminders:     5,
+			Clientid:         sql.NullInt64{Int64: clientId, Valid: true},
+			Url:              endpoint,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = botStorage.q.AddIncidentMessage(ctx, monitor_db.AddIncidentMessageParams{
+			Incidentid: incidentId,
+			Chatid:     clientId,
+			Messageid:  clientId,
+			Notifiedat: sql.NullTime{Time: alertedAt, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	muted, err := botStorage.q.MuteIncidentMessage(ctx, monitor_db.MuteIncidentMessageParams{Incidentid: incidentId, Chatid: 2})
+	if err != nil || muted != 1 {
+		t.Fatalf("expected incident to be muted in chat 2, got %d, %v", muted, err)
+	}
+	reminders, err := botStorage.q.GetPendingReminders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reminders) != 1 || reminders[0].Chatid != 1 {
+		t.Fatalf("expected reminder only in chat that did not mute, got %+v", reminders)
+	}
+
+	acknowledge := func() int64 {
+		acked, err := botStorage.q.AcknowledgeIncident(ctx, monitor_db.AcknowledgeIncidentParams{
+			Ackedby:   "@oncall",
+			Ackedbyid: 1,
+			Ackedat:   sql.NullTime{Time: time.Now(), Valid: true},
+			ID:        incidentId,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return acked
+	}
+	if acknowledge() != 1 {
+		t.Fatal("expected incident to be acknowledged")
+	}
+	if acknowledge() != 0 {
+		t.Error("expected incident to be acknowledged only once")
+	}
+	if reminders, _ := botStorage.q.GetPendingReminders(ctx); len(reminders) != 0 {
+		t.Errorf("expected no reminders for acknowledged incident, got %+v", reminders)
+	}
+}
+
+func TestSnoozedReminder(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	reminder := monitor_db.GetPendingRemindersRow{
+		Startedat:        now.Add(-time.Hour),
+		Reminderinterval: int64(30 * time.Minute / time.Second),
+		Snoozeduntil:     sql.NullTime{Time: now.Add(snoozeDuration), Valid: true},
+	}
+
+	if reminderDue(reminder, now) {
+		t.Error("expected snoozed reminder not to be due")
+	}
+	if !reminderDue(reminder, now.Add(snoozeDuration)) {
+		t.Error("expected reminder to be due after snooze")
+	}
+}
+
+func TestAcknowledgedContent(t *testing.T) {
+	ackedAt := time.Date(2024, 1, 1, 3, 7, 0, 0, time.UTC)
+	content := acknowledgedContent("endpoint is down", "@oncall", ackedAt, false)
+	if !strings.HasSuffix(content, "acked by @oncall at 03:07 UTC") || !strings.HasPrefix(content, "endpoint is down") {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	caption := acknowledgedContent(strings.Repeat("a", telegramCaptionLength), "@oncall", ackedAt, true)
+	if len(caption) > telegramCaptionLength || !strings.Contains(caption, "acked by @oncall") {
+		t.Errorf("expected caption to fit with acknowledgement, got %d bytes", len(caption))
+	}
+}
+
+func TestIncidentMessagesKeepTheirContent(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordIncidentMessage(ctx, incidentId, 1, &tele.Message{ID: 10, Caption: "alert", Document: &tele.Document{}})
+	recordIncidentMessage(ctx, incidentId, 2, &tele.Message{ID: 20, Text: "escalation"})
+
+	messages, err := botStorage.q.GetIncidentMessages(ctx, incidentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 incident messages, got %+v", messages)
+	}
+	for _, message := range messages {
+		switch message.Chatid {
+		case 1:
+			if message.Content != "alert" || !message.Document {
+				t.Errorf("expected document alert, got %+v", message)
+			}
+		case 2:
+			if message.Content != "escalation" || message.Document {
+				t.Errorf("expected text escalation, got %+v", message)
+			}
+		}
+	}
+}
+
+func TestIncidentButtonsOnlyInNotifiedChat(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordIncidentMessage(ctx, incidentId, 1, &tele.Message{ID: 10, Text: "alert"})
+
+	press := func(chatId int64, data string) tele.Context {
+		return (&tele.Bot{}).NewContext(tele.Update{Callback: &tele.Callback{
+			Data:    data,
+			Message: &tele.Message{Chat: &tele.Chat{ID: chatId}},
+		}})
+	}
+	data := strconv.FormatInt(incidentId, 10)
+	if _, found := chatIncident(ctx, press(1, data)); !found {
+		t.Error("expected incident to be found in the notified chat")
+	}
+	if _, found := chatIncident(ctx, press(2, data)); found {
+		t.Error("expected incident of another chat to be rejected")
+	}
+	if _, found := chatIncident(ctx, press(1, "forged")); found {
+		t.Error("expected invalid incident to be rejected")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"os"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupTestStorage
+// Bot storage over in-memory database with all migrations applied
+func setupTestStorage(t *testing.T) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would get its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := filepath.Glob("sqlc/migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations {
+		statements, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(statements)); err != nil {
+			t.Fatalf("apply %s: %s", migration, err)
+		}
+	}
+
+	secrets, _ := NewSecretBox("")
+	botStorage = BotStorage{
+		httpMonitor: NewHttpMonitor(MonitorConfig{AmountOfWorkers: 1}, secrets),
+		db:          db,
+		q:           monitor_db.New(db),
+		secrets:     secrets,
+	}
+}
+
+func subscribe(t *testing.T, clientId int64, endpoint string) {
+	t.Helper()
+
+	ctx := context.Background()
+	err := inTransaction(ctx, func(q *monitor_db.Queries) error {
+		return addClientSubscription(ctx, q, clientId, endpoint, "", "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syncMonitoredEndpoint(ctx, endpoint); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLastSubscriptionRemovesEndpoint(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	request := &EndpointRequest{Endpoint: endpoint}
+
+	subscribe(t, 1, endpoint)
+	subscribe(t, 2, endpoint)
+	err := botStorage.q.AddCheckResult(ctx, monitor_db.AddCheckResultParams{Url: endpoint, Checkedat: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeUserSubscription(ctx, 1, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if !botStorage.httpMonitor.RequestExists(request) {
+		t.Fatal("expected endpoint to be monitored for the other subscriber")
+	}
+
+	if err := removeUserSubscription(ctx, 2, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if botStorage.httpMonitor.RequestExists(request) {
+		t.Error("expected endpoint to be removed from monitor")
+	}
+	if _, err := botStorage.q.GetUrlIdToTrack(ctx, endpoint); err != sql.ErrNoRows {
+		t.Errorf("expected endpoint to be removed from db, got %v", err)
+	}
+
+	var results int
+	if err := botStorage.db.QueryRow("select count(*) from check_results").Scan(&results); err != nil {
+		t.Fatal(err)
+	}
+	if results != 0 {
+		t.Errorf("expected history of removed endpoint to be removed, got %d results", results)
+	}
+}
+
+func TestPausedSubscriptionStopsChecks(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	request := &EndpointRequest{Endpoint: endpoint}
+
+	subscribe(t, 1, endpoint)
+	if err := setSubscriptionPaused(ctx, 1, endpoint, true); err != nil {
+		t.Fatal(err)
+	}
+	if botStorage.httpMonitor.RequestExists(request) {
+		t.Fatal("expected paused endpoint to be removed from monitor")
+	}
+
+	// new subscriber resumes checks without resuming the paused subscription
+	subscribe(t, 2, endpoint)
+	if !botStorage.httpMonitor.RequestExists(request) {
+		t.Fatal("expected endpoint to be monitored for the new subscriber")
+	}
+
+	if err := removeUserSubscription(ctx, 2, endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if botStorage.httpMonitor.RequestExists(request) {
+		t.Fatal("expected endpoint with only paused subscription not to be monitored")
+	}
+	if _, err := botStorage.q.GetUrlIdToTrack(ctx, endpoint); err != nil {
+		t.Fatalf("expected paused endpoint to stay in db, got %v", err)
+	}
+
+	if err := setSubscriptionPaused(ctx, 1, endpoint, false); err != nil {
+		t.Fatal(err)
+	}
+	if !botStorage.httpMonitor.RequestExists(request) {
+		t.Error("expected resumed endpoint to be monitored")
+	}
+}
+
+func TestSubscriptionKeepsEndpointSecret(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	secrets, err := NewSecretBox(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	botStorage.secrets = secrets
+	endpoint := "redis://host:6379"
+
+	add := func(clientId int64, password string) error {
+		return inTransaction(ctx, func(q *monitor_db.Queries) error {
+			return addClientSubscription(ctx, q, clientId, endpoint, "", password)
+		})
+	}
+	if err := add(1, "first"); err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"other", ""} {
+		if err := add(2, password); !errors.Is(err, ErrSecretMismatch) {
+			t.Errorf("expected %q to be rejected, got %v", password, err)
+		}
+	}
+	if err := add(2, "first"); err != nil {
+		t.Fatalf("expected same credentials to be accepted, got %v", err)
+	}
+
+	urlId, err := botStorage.q.GetUrlIdToTrack(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := botStorage.q.GetEndpointSecret(ctx, urlId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, _ := secrets.Open(secret); password != "first" {
+		t.Errorf("expected secret of the first subscriber to be kept, got %q", password)
+	}
+}
+
+func TestSubscriptionKeepsEndpointSettings(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+
+	add := func(clientId int64, options ...string) error {
+		request := &EndpointRequest{Endpoint: endpoint}
+		if err := parseEndpointOptions(request, options); err != nil {
+			t.Fatal(err)
+		}
+		settings, err := encodeEndpointSettings(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inTransaction(ctx, func(q *monitor_db.Queries) error {
+			return addClientSubscription(ctx, q, clientId, endpoint, settings, "")
+		})
+	}
+	if err := add(1, "interval=5m", "retries=2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range [][]string{{"inverted=true"}, {"interval=5m"}, {}} {
+		if err := add(2, options...); !errors.Is(err, ErrSettingsMismatch) {
+			t.Errorf("expected %v to be rejected, got %v", options, err)
+		}
+	}
+	if err := add(2, "retries=2", "interval=5m"); err != nil {
+		t.Fatalf("expected same settings to be accepted, got %v", err)
+	}
+
+	settings, err := botStorage.q.GetEndpointSettings(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := &EndpointRequest{}
+	if err := decodeEndpointSettings(settings, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Interval != 5*time.Minute || stored.Retries != 2 || stored.Inverted {
+		t.Errorf("expected settings of the first subscriber to be kept, got %+v", stored)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"net/http/httptrace"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CheckTimings
+	// Phases are filled only for http checks,
+	// other kinds of checks have only total duration
+	CheckTimings struct {
+		DnsLookup    time.Duration `json:"dnsLookup"`
+		Connect      time.Duration `json:"connect"`
+		TlsHandshake time.Duration `json:"tlsHandshake"`
+		FirstByte    time.Duration `json:"firstByte"`
+		Transfer     time.Duration `json:"transfer"`
+		Total        time.Duration `json:"total"`
+	}
+
+	// CheckReport
+	// Everything observed during a single check
+	CheckReport struct {
+		Timings     CheckTimings
+		Diagnostics Diagnostics
+	}
+
+	CheckResult struct {
+		Endpoint  string
+		CheckedAt time.Time
+		Timings   CheckTimings
+		Error     error
+		Category  ErrorCategory
+	}
+
+	// checkTrace
+	// Trace callbacks are called from transport goroutines,
+	// so the report is guarded by the lock
+	checkTrace struct {
+		lock         sync.Mutex
+		report       *CheckReport
+		dnsStart     time.Time
+		connectStart time.Time
+		tlsStart     time.Time
+		wroteRequest time.Time
+		firstByte    time.Time
+	}
+)
+
+const (
+	historyRetention     = 30 * 24 * time.Hour
+	historyPruneInterval = time.Hour
+	resultsBufferSize    = 100
+)
+
+func newCheckTrace(report *CheckReport) *checkTrace {
+	return &checkTrace{report: report}
+}
+
+func (t *checkTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.report.Timings.DnsLookup = time.Since(t.dnsStart)
+			for _, addr := range info.Addrs {
+				t.report.Diagnostics.ResolvedAddrs = append(t.report.Diagnostics.ResolvedAddrs, addr.String())
+			}
+		},
+		ConnectStart: func(_, _ string) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			if err == nil {
+				t.report.Timings.Connect = time.Since(t.connectStart)
+				t.report.Diagnostics.RemoteAddr = addr
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.report.Timings.TlsHandshake = time.Since(t.tlsStart)
+			t.report.Diagnostics.Tls = &state
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.firstByte = time.Now()
+			t.report.Timings.FirstByte = t.firstByte.Sub(t.wroteRequest)
+		},
+	}
+}
+
+// bodyTransferred
+// Transfer is measured from the first byte of the response
+// until the body is read
+func (t *checkTrace) bodyTransferred() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.firstByte.IsZero() {
+		t.report.Timings.Transfer = time.Since(t.firstByte)
+	}
+}
+
+func (t CheckTimings) String() string {
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{name: "dns", duration: t.DnsLookup},
+		{name: "connect", duration: t.Connect},
+		{name: "tls", duration: t.TlsHandshake},
+		{name: "first byte", duration: t.FirstByte},
+		{name: "transfer", duration: t.Transfer},
+	}
+
+	var parts []string
+	for _, phase := range phases {
+		if phase.duration > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", phase.name, formatMilliseconds(phase.duration)))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("total %s", formatMilliseconds(t.Total)))
+
+	return strings.Join(parts, ", ")
+}
+
+func formatMilliseconds(duration time.Duration) string {
+	return fmt.Sprintf("%dms", duration.Milliseconds())
+}
+
+func timingsFromHistory(row monitor_db.GetCheckHistoryRow) CheckTimings {
+	return CheckTimings{
+		DnsLookup:    time.Duration(row.Dnsms) * time.Millisecond,
+		Connect:      time.Duration(row.Connectms) * time.Millisecond,
+		TlsHandshake: time.Duration(row.Tlsms) * time.Millisecond,
+		FirstByte:    time.Duration(row.Firstbytems) * time.Millisecond,
+		Transfer:     time.Duration(row.Transferms) * time.Millisecond,
+		Total:        time.Duration(row.Totalms) * time.Millisecond,
+	}
+}
+
+// recordResults
+// Results are written from a single goroutine,
+// so workers never wait for sqlite write lock
+func recordResults(ctx context.Context, q *monitor_db.Queries, results <-chan CheckResult) {
+	pruneTicker := time.NewTicker(historyPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pruneTicker.C:
+			retainedFrom := time.Now().Add(-historyRetention)
+			err := q.RemoveCheckResultsBefore(ctx, retainedFrom)
+			if err != nil {
+				log.Error().Err(err).Msg("prune check history")
+			}
+			if err := pruneIncidents(ctx, q, retainedFrom); err != nil {
+				log.Error().Err(err).Msg("prune resolved incidents")
+			}
+		case result := <-results:
+			errorText := ""
+			if result.Error != nil {
+				errorText = result.Error.Error()
+			}
+
+			err := q.AddCheckResult(ctx, monitor_db.AddCheckResultParams{
+				Checkedat:   result.CheckedAt,
+				Error:       errorText,
+				Category:    string(result.Category),
+				Dnsms:       result.Timings.DnsLookup.Milliseconds(),
+				Connectms:   result.Timings.Connect.Milliseconds(),
+				Tlsms:       result.Timings.TlsHandshake.Milliseconds(),
+				Firstbytems: result.Timings.FirstByte.Milliseconds(),
+				Transferms:  result.Timings.Transfer.Milliseconds(),
+				Totalms:     result.Timings.Total.Milliseconds(),
+				Url:         result.Endpoint,
+			})
+			if err != nil {
+				log.Error().Str("endpoint", result.Endpoint).Err(err).Msg("record check result")
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckLivelinessTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var report CheckReport
+	request := &EndpointRequest{Endpoint: server.URL}
+	if err := checkLiveliness(server.Client(), request, time.Second, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	timings := report.Timings
+
+	if timings.Connect <= 0 {
+		t.Errorf("expected connect duration, got %s", timings.Connect)
+	}
+	if timings.FirstByte < 20*time.Millisecond {
+		t.Errorf("expected first byte after handler delay, got %s", timings.FirstByte)
+	}
+}
+
+func TestCheckTimingsString(t *testing.T) {
+	timings := CheckTimings{
+		Connect:   2 * time.Millisecond,
+		FirstByte: 30 * time.Millisecond,
+		Total:     35 * time.Millisecond,
+	}
+
+	formatted := timings.String()
+	if formatted != "connect 2ms, first byte 30ms, total 35ms" {
+		t.Errorf("unexpected timings %q", formatted)
+	}
+	if strings.Contains(formatted, "dns") {
+		t.Error("phases that did not happen must be skipped")
+	}
+}
@@ -8,15 +8,35 @@ import (
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 type (
 	Config struct {
-		Token    string `yaml:"token"`
-		SqliteDB string `yaml:"sqliteDB"`
-		Monitor  struct {
-			AmountOfWorkers int `yaml:"amountOfWorkers"`
-		} `yaml:"monitor"`
+		Token     string        `yaml:"token"`
+		SqliteDB  string        `yaml:"sqliteDB"`
+		SecretKey string        `yaml:"secretKey"`
+		Monitor   MonitorConfig `yaml:"monitor"`
+		// Admins manage the worker pool and are notified when checks run late
+		Admins []int64 `yaml:"admins"`
+		// Checks are subscribed for their chats at startup
+		Checks []CheckConfig `yaml:"checks"`
+	}
+
+	MonitorConfig struct {
+		AmountOfWorkers int `yaml:"amountOfWorkers"`
+		// MaxWorkers above AmountOfWorkers enables autoscaling of the pool
+		// between them by the lag of the checks
+		MaxWorkers int `yaml:"maxWorkers"`
+		// LagAlert is the lag of the checks admins are notified about
+		LagAlert time.Duration `yaml:"lagAlert"`
+		// Jitter is a random delay up to this duration added to every check
+		Jitter time.Duration `yaml:"jitter"`
+		// Politeness limits, zero values mean unlimited
+		MaxChecksPerHost   int                        `yaml:"maxChecksPerHost"`
+		MinHostSpacing     time.Duration              `yaml:"minHostSpacing"`
+		MaxChecksPerSecond float64                    `yaml:"maxChecksPerSecond"`
+		HostLimits         map[string]HostLimitConfig `yaml:"hostLimits"`
 	}
 )
 
@@ -49,6 +69,16 @@ func loadConfig(configFile string) (*Config, error) {
 		config.Monitor.AmountOfWorkers = 1
 	}
 
+	for _, check := range config.Checks {
+		if _, err := check.request(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Monitor.LagAlert == 0 {
+		config.Monitor.LagAlert = defaultLagAlert
+	}
+
 	return config, nil
 }
 
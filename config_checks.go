@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+)
+
+type (
+	// CheckConfig
+	// Check from the config file is subscribed for its chats at startup.
+	// Check has the endpoint and the same settings as /add options,
+	// json schema may be written inline as yaml
+	CheckConfig struct {
+		Chats []int64   `yaml:"chats"`
+		Check yaml.Node `yaml:"check"`
+	}
+)
+
+var (
+	ErrInvalidCheckConfig = errors.New("invalid check in config file")
+)
+
+// request
+// Settings are decoded like the stored ones, so unknown options are rejected
+func (c CheckConfig) request() (*EndpointRequest, error) {
+	if len(c.Chats) == 0 {
+		return nil, fmt.Errorf("%w: check has no chats", ErrInvalidCheckConfig)
+	}
+	if c.Check.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%w: check must be a mapping of settings", ErrInvalidCheckConfig)
+	}
+
+	check := c.Check
+	check.Content = append([]*yaml.Node(nil), c.Check.Content...)
+	for i := 0; i+1 < len(check.Content); i += 2 {
+		if check.Content[i].Value != "jsonSchema" || check.Content[i+1].Kind == yaml.ScalarNode {
+			continue
+		}
+		schema, err := yaml.Marshal(check.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		check.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: string(schema)}
+	}
+
+	settings, err := yaml.Marshal(&check)
+	if err != nil {
+		return nil, err
+	}
+	request := new(EndpointRequest)
+	if err := decodeEndpointSettings(string(settings), request); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCheckConfig, err.Error())
+	}
+	if err := validateEndpoint(request.Endpoint); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCheckConfig, err.Error())
+	}
+	if err := validateEndpointSettings(request); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidCheckConfig, request.Endpoint, err.Error())
+	}
+
+	return request, nil
+}
+
+// subscribeConfiguredChecks
+// Config file is the source of the settings of its checks,
+// they replace the stored ones on every start.
+// Chat that already has the subscription keeps it, so restarts do not repeat it.
+// Runs before the monitor loads the endpoints to check
+func subscribeConfiguredChecks(ctx context.Context, checks []CheckConfig) error {
+	for _, check := range checks {
+		request, err := check.request()
+		if err != nil {
+			return err
+		}
+
+		endpoint, password, err := splitEndpointSecret(request.Endpoint)
+		if err != nil {
+			return err
+		}
+		request.Endpoint = endpoint
+		settings, err := encodeEndpointSettings(request)
+		if err != nil {
+			return err
+		}
+
+		err = inTransaction(ctx, func(q *monitor_db.Queries) error {
+			if _, err := insertEndpointOrGetId(ctx, q, endpoint, settings); err != nil {
+				return err
+			}
+			err := q.UpdateEndpointSettings(ctx, monitor_db.UpdateEndpointSettingsParams{
+				Settings: settings,
+				Url:      endpoint,
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, chatId := range check.Chats {
+				subscribed, err := hasSubscription(ctx, q, chatId, endpoint)
+				if err != nil {
+					return err
+				}
+				if subscribed {
+					continue
+				}
+				if err := addClientSubscription(ctx, q, chatId, endpoint, settings, password); err != nil {
+					return fmt.Errorf("subscribe chat %d to %s: %w", chatId, endpoint, err)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("endpoint", endpoint).Ints64("chats", check.Chats).Msg("check from config is subscribed")
+	}
+
+	return nil
+}
+
+func hasSubscription(ctx context.Context, q *monitor_db.Queries, clientId int64, endpoint string) (bool, error) {
+	subscriptions, err := q.GetUserSubscriptions(ctx, sql.NullInt64{Int64: clientId, Valid: true})
+	if err != nil {
+		return false, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Url == endpoint {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const checksConfig = `
+token: token
+sqliteDB: monitor.db
+checks:
+  - chats: [1, 2]
+    check:
+      endpoint: https://api.com/items
+      interval: 5m
+      jsonSchema:
+        type: array
+        items: {required: [id]}
+`
+
+func writeConfig(t *testing.T, content string) string {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return configFile
+}
+
+func TestChecksFromConfig(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com/items"
+
+	config, err := loadConfig(writeConfig(t, checksConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// restart does not subscribe the chats again
+	for i := 0; i < 2; i++ {
+		if err := subscribeConfiguredChecks(ctx, config.Checks); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, chatId := range []int64{1, 2} {
+		subscriptions, err := botStorage.q.GetUserSubscriptions(ctx, sql.NullInt64{Int64: chatId, Valid: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subscriptions) != 1 || subscriptions[0].Url != endpoint {
+			t.Errorf("chat %d: expected single subscription to %s, got %+v", chatId, endpoint, subscriptions)
+		}
+	}
+
+	settings, err := botStorage.q.GetEndpointSettings(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &EndpointRequest{Endpoint: endpoint}
+	if err := decodeEndpointSettings(settings, request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Interval != 5*time.Minute {
+		t.Errorf("expected interval from config, got %s", request.Interval)
+	}
+	if err := checkJsonSchema(request, []byte(`[{"id": 1}]`)); err != nil {
+		t.Errorf("expected valid response, got %v", err)
+	}
+	if err := checkJsonSchema(request, []byte(`[{"name": "item"}]`)); err == nil {
+		t.Error("expected response without id to violate inline schema")
+	}
+
+	// changed config replaces the stored settings
+	config.Checks[0].Check.Content[3].Value = "10m"
+	if err := subscribeConfiguredChecks(ctx, config.Checks); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := botStorage.q.GetEndpointSettings(ctx, endpoint)
+	request = &EndpointRequest{Endpoint: endpoint}
+	if err := decodeEndpointSettings(stored, request); err != nil || request.Interval != 10*time.Minute {
+		t.Errorf("expected settings from changed config, got %s, %v", request.Interval, err)
+	}
+}
+
+func TestInvalidCheckInConfig(t *testing.T) {
+	for _, check := range []string{
+		"  - chats: [1]\n    check: {endpoint: https://api.com, unknown: 1}\n",
+		"  - chats: [1]\n    check: {endpoint: ftp://api.com}\n",
+		"  - chats: [1]\n    check: {endpoint: https://api.com, interval: 1s}\n",
+		"  - check: {endpoint: https://api.com}\n",
+	} {
+		_, err := loadConfig(writeConfig(t, "token: token\nsqliteDB: monitor.db\nchecks:\n"+check))
+		if !errors.Is(err, ErrInvalidCheckConfig) {
+			t.Errorf("expected check to be invalid:\n%s\ngot %v", check, err)
+		}
+	}
+}
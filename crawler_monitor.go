@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	crawledPage struct {
+		url         string
+		status      int
+		contentType string
+		body        []byte
+		err         error
+	}
+
+	sitemapDocument struct {
+		Urls     []sitemapLocation `xml:"url"`
+		Sitemaps []sitemapLocation `xml:"sitemap"`
+	}
+
+	sitemapLocation struct {
+		Loc string `xml:"loc"`
+	}
+)
+
+const (
+	crawlModeSitemap = "sitemap"
+	crawlModeLinks   = "links"
+
+	defaultCrawlDepth  = 2
+	defaultCrawlBudget = 100
+	crawlConcurrency   = 4
+	maxCrawledBodySize = 5 << 20
+	maxReportedPages   = 20
+)
+
+var (
+	ErrUnknownCrawlMode = errors.New("unknown crawl mode")
+)
+
+// checkCrawl
+// Discovers pages from sitemap.xml or by following same-origin links
+// from the endpoint and checks every page within the crawl budget.
+// All broken pages are reported as a single error
+func checkCrawl(client *http.Client, r *EndpointRequest, timeout time.Duration) error {
+	budget := r.CrawlBudget
+	if budget <= 0 {
+		budget = defaultCrawlBudget
+	}
+
+	var pages []crawledPage
+	switch r.Crawl {
+	case crawlModeSitemap:
+		pageUrls, err := collectSitemapUrls(client, sitemapUrl(r.Endpoint), budget, timeout)
+		if err != nil {
+			return fmt.Errorf("read sitemap: %w", err)
+		}
+		pages = fetchPages(client, pageUrls, false, timeout)
+	case crawlModeLinks:
+		depth := r.CrawlDepth
+		if depth <= 0 {
+			depth = defaultCrawlDepth
+		}
+		pages = crawlLinks(client, r.Endpoint, depth, budget, timeout)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCrawlMode, r.Crawl)
+	}
+
+	return brokenPagesError(pages)
+}
+
+func sitemapUrl(endpoint string) string {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || strings.HasSuffix(endpointUrl.Path, ".xml") {
+		return endpoint
+	}
+
+	return endpointUrl.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String()
+}
+
+// collectSitemapUrls
+// Reads page locations from the sitemap,
+// nested sitemaps of a sitemap index are read until the budget is reached
+func collectSitemapUrls(client *http.Client, sitemap string, budget int, timeout time.Duration) ([]string, error) {
+	var pageUrls []string
+	sitemaps := []string{sitemap}
+	visited := map[string]bool{sitemap: true}
+
+	for len(sitemaps) > 0 && len(pageUrls) < budget {
+		page := fetchPage(client, sitemaps[0], true, timeout)
+		sitemaps = sitemaps[1:]
+		if page.err != nil {
+			return nil, page.err
+		}
+		if page.status >= http.StatusBadRequest {
+			return nil, fmt.Errorf("%s returned %d", page.url, page.status)
+		}
+
+		var document sitemapDocument
+		if err := xml.Unmarshal(page.body, &document); err != nil {
+			return nil, err
+		}
+
+		for _, location := range document.Urls {
+			if len(pageUrls) == budget {
+				break
+			}
+			pageUrls = append(pageUrls, strings.TrimSpace(location.Loc))
+		}
+
+		for _, location := range document.Sitemaps {
+			loc := strings.TrimSpace(location.Loc)
+			if !visited[loc] {
+				visited[loc] = true
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	}
+
+	return pageUrls, nil
+}
+
+// crawlLinks
+// Breadth-first crawl from the start page, depth 1 checks
+// the start page and every same-origin page it links to
+func crawlLinks(client *http.Client, start string, depth, budget int, timeout time.Duration) []crawledPage {
+	var pages []crawledPage
+	visited := map[string]bool{start: true}
+	level := []string{start}
+
+	for currentDepth := 0; len(level) > 0; currentDepth++ {
+		followLinks := currentDepth < depth
+		levelPages := fetchPages(client, level, followLinks, timeout)
+		pages = append(pages, levelPages...)
+
+		level = nil
+		if !followLinks {
+			break
+		}
+
+		for _, page := range levelPages {
+			if page.err != nil || !strings.HasPrefix(page.contentType, "text/html") {
+				continue
+			}
+
+			for _, link := range sameOriginLinks(page.url, page.body) {
+				if visited[link] || len(visited) >= budget {
+					continue
+				}
+				visited[link] = true
+				level = append(level, link)
+			}
+		}
+	}
+
+	return pages
+}
+
+func sameOriginLinks(pageUrl string, body []byte) []string {
+	base, err := url.Parse(pageUrl)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	tokenizer := html.NewTokenizer(strings.NewReader(string(body)))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return links
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		if token.Data != "a" {
+			continue
+		}
+
+		for _, attribute := range token.Attr {
+			if attribute.Key != "href" {
+				continue
+			}
+
+			link, err := base.Parse(strings.TrimSpace(attribute.Val))
+			if err != nil || link.Scheme != base.Scheme || link.Host != base.Host {
+				continue
+			}
+			link.Fragment = ""
+			links = append(links, link.String())
+		}
+	}
+}
+
+func fetchPages(client *http.Client, pageUrls []string, readBody bool, timeout time.Duration) []crawledPage {
+	pages := make([]crawledPage, len(pageUrls))
+	semaphore := make(chan struct{}, crawlConcurrency)
+
+	var wg sync.WaitGroup
+	for id, pageUrl := range pageUrls {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(id int, pageUrl string) {
+			defer wg.Done()
+			pages[id] = fetchPage(client, pageUrl, readBody, timeout)
+			<-semaphore
+		}(id, pageUrl)
+	}
+	wg.Wait()
+
+	return pages
+}
+
+func fetchPage(client *http.Client, pageUrl string, readBody bool, timeout time.Duration) crawledPage {
+	page := crawledPage{url: pageUrl}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, pageUrl, nil)
+	if err != nil {
+		page.err = err
+		return page
+	}
+
+	res, err := client.Do(request)
+	if err != nil {
+		page.err = err
+		return page
+	}
+	defer res.Body.Close()
+
+	page.status = res.StatusCode
+	page.contentType = res.Header.Get("Content-Type")
+	if readBody {
+		page.body, page.err = io.ReadAll(io.LimitReader(res.Body, maxCrawledBodySize))
+	}
+
+	return page
+}
+
+func brokenPagesError(pages []crawledPage) error {
+	var broken []string
+	for _, page := range pages {
+		switch {
+		case page.err != nil:
+			broken = append(broken, fmt.Sprintf("error %s: %s", page.url, page.err.Error()))
+		case page.status >= http.StatusBadRequest:
+			broken = append(broken, fmt.Sprintf("%d %s", page.status, page.url))
+		}
+	}
+
+	if len(broken) == 0 {
+		return nil
+	}
+
+	report := fmt.Sprintf("%d of %d pages are broken:\n", len(broken), len(pages))
+	if len(broken) > maxReportedPages {
+		report += strings.Join(broken[:maxReportedPages], "\n")
+		report += fmt.Sprintf("\n... and %d more", len(broken)-maxReportedPages)
+	} else {
+		report += strings.Join(broken, "\n")
+	}
+
+	return errors.New(report)
+}
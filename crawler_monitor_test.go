@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/about">about</a> <a href="/missing#top">missing</a> <a href="https://other.example/">other</a>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/">home</a> <a href="/deep">deep</a>`)
+	})
+	mux.HandleFunc("/deep", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/pages.xml</loc></sitemap></sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset><url><loc>%[1]s/</loc></url><url><loc>%[1]s/about</loc></url><url><loc>%[1]s/gone</loc></url></urlset>`, server.URL)
+	})
+
+	return server
+}
+
+func TestCheckCrawlSitemap(t *testing.T) {
+	server := newTestSite(t)
+
+	err := checkCrawl(server.Client(), &EndpointRequest{Endpoint: server.URL, Crawl: crawlModeSitemap}, time.Second)
+	if err == nil {
+		t.Fatal("expected broken page to be reported")
+	}
+	if !strings.HasPrefix(err.Error(), "1 of 3 pages are broken") || !strings.Contains(err.Error(), "404 "+server.URL+"/gone") {
+		t.Errorf("unexpected report: %s", err)
+	}
+}
+
+func TestCheckCrawlLinks(t *testing.T) {
+	server := newTestSite(t)
+
+	err := checkCrawl(server.Client(), &EndpointRequest{Endpoint: server.URL + "/", Crawl: crawlModeLinks, CrawlDepth: 1}, time.Second)
+	if err == nil || !strings.HasPrefix(err.Error(), "1 of 3 pages are broken") {
+		t.Fatalf("expected only /missing to be broken at depth 1, got %v", err)
+	}
+
+	err = checkCrawl(server.Client(), &EndpointRequest{Endpoint: server.URL + "/", Crawl: crawlModeLinks, CrawlDepth: 2}, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "500 "+server.URL+"/deep") {
+		t.Fatalf("expected /deep to be reported at depth 2, got %v", err)
+	}
+
+	err = checkCrawl(server.Client(), &EndpointRequest{Endpoint: server.URL + "/", Crawl: crawlModeLinks, CrawlBudget: 2}, time.Second)
+	if err != nil {
+		t.Errorf("expected budget to stop before broken pages, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// CronSchedule
+	// Standard five field cron expression with optional timezone,
+	// e.g. */5 9-18 * * MON-FRI Europe/Berlin.
+	// Like in cron, when both day of month and day of week are restricted
+	// the day matches either of them
+	CronSchedule struct {
+		minute     uint64
+		hour       uint64
+		dayOfMonth uint64
+		month      uint64
+		dayOfWeek  uint64
+		anyDay     bool
+		anyWeekday bool
+		location   *time.Location
+	}
+
+	cronField struct {
+		name  string
+		min   int
+		max   int
+		names map[string]int
+	}
+)
+
+var (
+	ErrInvalidCron = errors.New("invalid cron expression")
+
+	cronMinute     = cronField{name: "minute", min: 0, max: 59}
+	cronHour       = cronField{name: "hour", min: 0, max: 23}
+	cronDayOfMonth = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth      = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// 7 is accepted as sunday and folded into 0
+	cronDayOfWeek = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+const (
+	cronSearchYears = 5
+)
+
+func ParseCron(expression string) (*CronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, fmt.Errorf("%w: expected 5 fields and optional timezone, got %q", ErrInvalidCron, expression)
+	}
+
+	schedule := &CronSchedule{location: time.UTC}
+	if len(fields) == 6 {
+		location, err := time.LoadLocation(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCron, err)
+		}
+		schedule.location = location
+	}
+
+	var err error
+	if schedule.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = cronHour.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfMonth, err = cronDayOfMonth.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek, err = cronDayOfWeek.parse(fields[4]); err != nil {
+		return nil, err
+	}
+
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+	schedule.anyDay = strings.HasPrefix(fields[2], "*")
+	schedule.anyWeekday = strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+// parse
+// Accepts lists of values, ranges and steps: 1,15 9-18 */5 10-50/10
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepValue, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			parsedStep, err := strconv.Atoi(stepValue)
+			if err != nil || parsedStep <= 0 {
+				return 0, fmt.Errorf("%w: invalid %s step %q", ErrInvalidCron, f.name, stepValue)
+			}
+			step = parsedStep
+		}
+
+		start, end := f.min, f.max
+		if valueRange != "*" {
+			first, last, isRange := strings.Cut(valueRange, "-")
+
+			var err error
+			if start, err = f.value(first); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = f.value(last); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = f.max
+			}
+		}
+
+		if start > end {
+			return 0, fmt.Errorf("%w: %s range %s is reversed", ErrInvalidCron, f.name, valueRange)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f cronField) value(value string) (int, error) {
+	if named, ok := f.names[strings.ToUpper(value)]; ok {
+		return named, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("%w: %s %q must be from %d to %d", ErrInvalidCron, f.name, value, f.min, f.max)
+	}
+
+	return number, nil
+}
+
+// Next
+// Returns the first matching minute after the given time,
+// zero time when nothing matches within several years, e.g. for 30 FEB
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	lastYear := t.Year() + cronSearchYears
+
+	for t.Year() <= lastYear {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if c.anyDay || c.anyWeekday {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err.Error())
+	}
+
+	cases := []struct {
+		expression string
+		after      time.Time
+		next       time.Time
+	}{
+		{
+			expression: "*/5 9-18 * * MON-FRI Europe/Berlin",
+			after:      time.Date(2024, 3, 8, 18, 57, 0, 0, berlin),
+			next:       time.Date(2024, 3, 11, 9, 0, 0, 0, berlin),
+		},
+		{
+			expression: "*/5 9-18 * * MON-FRI Europe/Berlin",
+			after:      time.Date(2024, 3, 11, 10, 2, 30, 0, berlin),
+			next:       time.Date(2024, 3, 11, 10, 5, 0, 0, berlin),
+		},
+		{
+			expression: "30 6 1,15 * *",
+			after:      time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC),
+			next:       time.Date(2024, 2, 1, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			expression: "0 0 29 FEB *",
+			after:      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			next:       time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// day of month or day of week when both are restricted
+			expression: "0 12 1 * SUN",
+			after:      time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC),
+			next:       time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			// 02:15 does not exist when the clocks are moved forward
+			expression: "15 2 * * 7 Europe/Berlin",
+			after:      time.Date(2024, 3, 30, 12, 0, 0, 0, berlin),
+			next:       time.Date(2024, 4, 7, 2, 15, 0, 0, berlin),
+		},
+	}
+
+	for _, c := range cases {
+		schedule, err := ParseCron(c.expression)
+		if err != nil {
+			t.Fatalf("%s: %s", c.expression, err)
+		}
+		if next := schedule.Next(c.after); !next.Equal(c.next) {
+			t.Errorf("%s after %s: expected %s, got %s", c.expression, c.after, c.next, next)
+		}
+	}
+
+	schedule, _ := ParseCron("0 0 30 FEB *")
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected impossible schedule to have no next run, got %s", next)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expression := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 18-9 * * *",
+		"*/0 * * * *",
+		"* * * JANUARY *",
+		"* * * * * Mars/Olympus",
+	} {
+		if _, err := ParseCron(expression); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("expected %q to be invalid, got %v", expression, err)
+		}
+	}
+}
+
+func TestEditOptionsResetValue(t *testing.T) {
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Minute}
+	if err := parseEndpointOptions(request, []string{"interval=", "cron=0 9 * * *"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateEndpointSettings(request); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Interval != 0 || request.cronSchedule == nil {
+		t.Errorf("expected interval to be replaced by cron, got %+v", request)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDatabaseQuery = "SELECT 1"
+	defaultRedisPort     = "6379"
+	defaultMysqlPort     = "3306"
+)
+
+var (
+	infoOperators = []string{">=", "<=", "!=", "=", ">", "<"}
+)
+
+// splitEndpointSecret
+// Removes password from the database endpoint so it is never stored
+// or shown in plain text, password is returned separately
+// to be kept as an encrypted monitor secret.
+// Other endpoints are kept as is, http checks send their userinfo as basic auth
+func splitEndpointSecret(endpoint string) (string, string, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch endpointUrl.Scheme {
+	case "redis", "rediss", "postgres", "postgresql", "mysql":
+	default:
+		return endpoint, "", nil
+	}
+
+	if endpointUrl.User == nil {
+		return endpoint, "", nil
+	}
+
+	password, hasPassword := endpointUrl.User.Password()
+	if !hasPassword {
+		return endpoint, "", nil
+	}
+
+	if username := endpointUrl.User.Username(); len(username) > 0 {
+		endpointUrl.User = url.User(username)
+	} else {
+		endpointUrl.User = nil
+	}
+
+	return endpointUrl.String(), password, nil
+}
+
+// checkSqlDatabase
+// Logs into postgres or mysql and runs a scalar query,
+// "SELECT 1" by default. Result is matched with expect pattern when it is set
+func checkSqlDatabase(endpointUrl *url.URL, r *EndpointRequest, timeout time.Duration) error {
+	driverName, dsn := databaseDsn(endpointUrl, r.password, timeout)
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	query := r.Query
+	if len(query) == 0 {
+		query = defaultDatabaseQuery
+	}
+
+	var value sql.NullString
+	err = db.QueryRowContext(ctx, query).Scan(&value)
+	if err != nil {
+		return err
+	}
+
+	return matchExpected("query", value.String, r.Expect)
+}
+
+func databaseDsn(endpointUrl *url.URL, password string, timeout time.Duration) (string, string) {
+	username := endpointUrl.User.Username()
+
+	if endpointUrl.Scheme == "mysql" {
+		config := mysql.NewConfig()
+		config.User = username
+		config.Passwd = password
+		config.Net = "tcp"
+		config.Addr = hostWithDefaultPort(endpointUrl, defaultMysqlPort)
+		config.DBName = strings.TrimPrefix(endpointUrl.Path, "/")
+		config.Timeout = timeout
+		config.TLSConfig = endpointUrl.Query().Get("tls")
+		return "mysql", config.FormatDSN()
+	}
+
+	dsnUrl := *endpointUrl
+	dsnUrl.User = url.UserPassword(username, password)
+	query := dsnUrl.Query()
+	query.Set("connect_timeout", strconv.Itoa(max(int(timeout.Seconds()), 1)))
+	dsnUrl.RawQuery = query.Encode()
+
+	return "postgres", dsnUrl.String()
+}
+
+// checkRedis
+// Authenticates when the endpoint has a password, expects PONG to PING
+// and compares INFO fields with the conditions from info setting,
+// e.g. role=master,connected_slaves>=1
+func checkRedis(endpointUrl *url.URL, r *EndpointRequest, timeout time.Duration) error {
+	address := hostWithDefaultPort(endpointUrl, defaultRedisPort)
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	if endpointUrl.Scheme == "rediss" {
+		conn = tls.Client(conn, &tls.Config{ServerName: endpointUrl.Hostname()})
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	if len(r.password) > 0 {
+		authArgs := []string{"AUTH", r.password}
+		if username := endpointUrl.User.Username(); len(username) > 0 {
+			authArgs = []string{"AUTH", username, r.password}
+		}
+		if _, err := redisCommand(conn, reader, authArgs...); err != nil {
+			return fmt.Errorf("redis auth: %w", err)
+		}
+	}
+
+	if database := strings.TrimPrefix(endpointUrl.Path, "/"); len(database) > 0 {
+		if _, err := redisCommand(conn, reader, "SELECT", database); err != nil {
+			return fmt.Errorf("redis select %s: %w", database, err)
+		}
+	}
+
+	pong, err := redisCommand(conn, reader, "PING")
+	if err != nil {
+		return err
+	}
+	if pong != "PONG" {
+		return fmt.Errorf("redis replied %q to PING", pong)
+	}
+
+	if len(r.Info) == 0 {
+		return nil
+	}
+
+	info, err := redisCommand(conn, reader, "INFO")
+	if err != nil {
+		return err
+	}
+
+	return checkRedisInfo(info, r.Info)
+}
+
+func redisCommand(conn net.Conn, reader *bufio.Reader, args ...string) (string, error) {
+	var command strings.Builder
+	command.WriteString(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		command.WriteString(fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg))
+	}
+
+	if _, err := conn.Write([]byte(command.String())); err != nil {
+		return "", err
+	}
+
+	return readRedisReply(reader)
+}
+
+func readRedisReply(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return "", errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis error: %s", line[1:])
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", err
+		}
+		if length < 0 {
+			return "", nil
+		}
+		bulk := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, bulk); err != nil {
+			return "", err
+		}
+		return string(bulk[:length]), nil
+	}
+
+	return "", fmt.Errorf("unsupported redis reply: %q", truncate(line, 32))
+}
+
+func checkRedisInfo(info, conditions string) error {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if found && !strings.HasPrefix(key, "#") {
+			fields[key] = value
+		}
+	}
+
+	for _, condition := range strings.Split(conditions, ",") {
+		if err := checkInfoCondition(fields, strings.TrimSpace(condition)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkInfoCondition(fields map[string]string, condition string) error {
+	for _, operator := range infoOperators {
+		field, expected, found := strings.Cut(condition, operator)
+		if !found {
+			continue
+		}
+
+		actual, ok := fields[field]
+		if !ok {
+			return fmt.Errorf("redis info has no field %s", field)
+		}
+
+		if compareInfoValues(actual, operator, expected) {
+			return nil
+		}
+		return &AssertionError{Err: fmt.Errorf("redis info %s is %s, expected %s%s", field, actual, operator, expected)}
+	}
+
+	return fmt.Errorf("invalid info condition %s", condition)
+}
+
+func compareInfoValues(actual, operator, expected string) bool {
+	switch operator {
+	case "=":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	}
+
+	actualNumber, actualErr := strconv.ParseFloat(actual, 64)
+	expectedNumber, expectedErr := strconv.ParseFloat(expected, 64)
+	if actualErr != nil || expectedErr != nil {
+		return false
+	}
+
+	switch operator {
+	case ">=":
+		return actualNumber >= expectedNumber
+	case "<=":
+		return actualNumber <= expectedNumber
+	case ">":
+		return actualNumber > expectedNumber
+	case "<":
+		return actualNumber < expectedNumber
+	}
+
+	return false
+}
+
+func hostWithDefaultPort(endpointUrl *url.URL, defaultPort string) string {
+	if len(endpointUrl.Port()) > 0 {
+		return endpointUrl.Host
+	}
+
+	return net.JoinHostPort(endpointUrl.Hostname(), defaultPort)
+}
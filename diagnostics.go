@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	tele "gopkg.in/telebot.v3"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type (
+	// Diagnostics
+	// Details of the connection and the response
+	// that are collected during the check
+	Diagnostics struct {
+		ResolvedAddrs []string
+		RemoteAddr    string
+		Tls           *tls.ConnectionState
+		StatusLine    string
+		Header        http.Header
+		BodyHead      []byte
+	}
+)
+
+const (
+	diagnosticsBodyBytes     = 4 << 10
+	diagnosticsLookupTimeout = 2 * time.Second
+	diagnosticsFileName      = "diagnostics.txt"
+	telegramCaptionLength    = 1024
+)
+
+var (
+	redactedHeaders = []string{"Set-Cookie", "Authorization", "Proxy-Authenticate"}
+)
+
+func (d *Diagnostics) setResponse(res *http.Response, body []byte) {
+	d.StatusLine = fmt.Sprintf("%s %s", res.Proto, res.Status)
+	d.Header = res.Header.Clone()
+	d.BodyHead = body[:min(len(body), diagnosticsBodyBytes)]
+}
+
+// formatDiagnostics
+// Builds a plain text bundle for the failed check,
+// dns is resolved again because non http checks do not trace it
+func formatDiagnostics(r *EndpointRequest, checkErr error, checkedAt time.Time, report *CheckReport) string {
+	var bundle strings.Builder
+
+	fmt.Fprintf(&bundle, "endpoint: %s\n", r.Endpoint)
+	fmt.Fprintf(&bundle, "checked at: %s\n", checkedAt.UTC().Format(time.RFC3339))
+	if checkErr != nil {
+		fmt.Fprintf(&bundle, "error: %s\n", checkErr)
+	}
+	fmt.Fprintf(&bundle, "timings: %s\n", report.Timings)
+
+	writeErrorChain(&bundle, checkErr)
+	writeDns(&bundle, r.Endpoint, report.Diagnostics.ResolvedAddrs)
+	writeConnection(&bundle, &report.Diagnostics)
+	writeTls(&bundle, report.Diagnostics.Tls, checkErr)
+	writeResponse(&bundle, &report.Diagnostics)
+
+	return bundle.String()
+}
+
+func writeErrorChain(bundle *strings.Builder, checkErr error) {
+	if checkErr == nil {
+		return
+	}
+
+	bundle.WriteString("\nerror chain:\n")
+	for err := checkErr; err != nil; err = errors.Unwrap(err) {
+		fmt.Fprintf(bundle, "  %T: %s\n", err, err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(checkErr, &opErr) {
+		fmt.Fprintf(bundle, "  operation: %s %s", opErr.Op, opErr.Net)
+		if opErr.Source != nil {
+			fmt.Fprintf(bundle, " from %s", opErr.Source)
+		}
+		if opErr.Addr != nil {
+			fmt.Fprintf(bundle, " to %s", opErr.Addr)
+		}
+		bundle.WriteString("\n")
+	}
+
+	var errno syscall.Errno
+	if errors.As(checkErr, &errno) {
+		fmt.Fprintf(bundle, "  errno: %d (%s)\n", uint(errno), errno)
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(checkErr, &dnsErr) {
+		fmt.Fprintf(
+			bundle,
+			"  dns: name %s, server %s, not found %t, timeout %t\n",
+			dnsErr.Name,
+			dnsErr.Server,
+			dnsErr.IsNotFound,
+			dnsErr.IsTimeout,
+		)
+	}
+}
+
+func writeDns(bundle *strings.Builder, endpoint string, resolvedAddrs []string) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || len(endpointUrl.Hostname()) == 0 {
+		return
+	}
+
+	host := endpointUrl.Hostname()
+	bundle.WriteString("\ndns:\n")
+	if net.ParseIP(host) != nil {
+		fmt.Fprintf(bundle, "  %s is an ip address\n", host)
+		return
+	}
+
+	if len(resolvedAddrs) > 0 {
+		fmt.Fprintf(bundle, "  used by check: %s\n", strings.Join(resolvedAddrs, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsLookupTimeout)
+	defer cancel()
+
+	cname, err := net.DefaultResolver.LookupCNAME(ctx, host)
+	if err == nil && strings.TrimSuffix(cname, ".") != host {
+		fmt.Fprintf(bundle, "  cname: %s\n", cname)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		fmt.Fprintf(bundle, "  lookup %s: %s\n", host, err)
+		return
+	}
+	for _, addr := range addrs {
+		fmt.Fprintf(bundle, "  %s\n", addr)
+	}
+}
+
+func writeConnection(bundle *strings.Builder, diagnostics *Diagnostics) {
+	if len(diagnostics.RemoteAddr) == 0 {
+		return
+	}
+
+	fmt.Fprintf(bundle, "\nconnected to: %s\n", diagnostics.RemoteAddr)
+}
+
+func writeTls(bundle *strings.Builder, state *tls.ConnectionState, checkErr error) {
+	certificate := certificateFromError(checkErr)
+	if state == nil && certificate == nil {
+		return
+	}
+
+	bundle.WriteString("\ntls:\n")
+	if state != nil && state.HandshakeComplete {
+		fmt.Fprintf(bundle, "  version: %s\n", tls.VersionName(state.Version))
+		fmt.Fprintf(bundle, "  cipher suite: %s\n", tls.CipherSuiteName(state.CipherSuite))
+		fmt.Fprintf(bundle, "  server name: %s\n", state.ServerName)
+		if len(state.NegotiatedProtocol) > 0 {
+			fmt.Fprintf(bundle, "  alpn: %s\n", state.NegotiatedProtocol)
+		}
+	}
+	if state != nil && !state.HandshakeComplete {
+		bundle.WriteString("  handshake is not complete\n")
+	}
+
+	if certificate == nil && state != nil && len(state.PeerCertificates) > 0 {
+		certificate = state.PeerCertificates[0]
+	}
+	if certificate != nil {
+		fmt.Fprintf(bundle, "  certificate subject: %s\n", certificate.Subject)
+		fmt.Fprintf(bundle, "  certificate issuer: %s\n", certificate.Issuer)
+		fmt.Fprintf(bundle, "  certificate dns names: %s\n", strings.Join(certificate.DNSNames, ", "))
+		fmt.Fprintf(
+			bundle,
+			"  certificate valid: %s - %s\n",
+			certificate.NotBefore.UTC().Format(time.RFC3339),
+			certificate.NotAfter.UTC().Format(time.RFC3339),
+		)
+	}
+}
+
+// certificateFromError
+// Verification errors carry the certificate that was rejected
+func certificateFromError(checkErr error) *x509.Certificate {
+	var hostnameErr x509.HostnameError
+	if errors.As(checkErr, &hostnameErr) {
+		return hostnameErr.Certificate
+	}
+
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(checkErr, &invalidErr) {
+		return invalidErr.Cert
+	}
+
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(checkErr, &authorityErr) {
+		return authorityErr.Cert
+	}
+
+	var verificationErr *tls.CertificateVerificationError
+	if errors.As(checkErr, &verificationErr) && len(verificationErr.UnverifiedCertificates) > 0 {
+		return verificationErr.UnverifiedCertificates[0]
+	}
+
+	return nil
+}
+
+func writeResponse(bundle *strings.Builder, diagnostics *Diagnostics) {
+	if len(diagnostics.StatusLine) == 0 {
+		return
+	}
+
+	fmt.Fprintf(bundle, "\nresponse:\n%s\n", diagnostics.StatusLine)
+
+	names := make([]string, 0, len(diagnostics.Header))
+	for name := range diagnostics.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		value := strings.Join(diagnostics.Header[name], ", ")
+		if slices.Contains(redactedHeaders, name) {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(bundle, "%s: %s\n", name, value)
+	}
+
+	if len(diagnostics.BodyHead) > 0 {
+		fmt.Fprintf(bundle, "\nfirst %d bytes of body:\n%s\n", len(diagnostics.BodyHead), diagnostics.BodyHead)
+	}
+}
+
+// diagnosticsDocument
+// Telegram limits document caption,
+// so long messages are cut and the full error stays in the bundle
+func diagnosticsDocument(message, diagnostics string) *tele.Document {
+	return &tele.Document{
+		File:     tele.FromReader(strings.NewReader(diagnostics)),
+		FileName: diagnosticsFileName,
+		MIME:     "text/plain",
+		Caption:  truncate(message, telegramCaptionLength-len("...")),
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFormatDiagnosticsIncludesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Upstream", "backend-2")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream timed out"))
+	}))
+	defer server.Close()
+
+	var report CheckReport
+	request := &EndpointRequest{Endpoint: server.URL}
+	err := checkLiveliness(server.Client(), request, time.Second, &report)
+	if err == nil {
+		t.Fatal("expected bad gateway to fail")
+	}
+
+	bundle := formatDiagnostics(request, err, time.Now(), &report)
+	for _, expected := range []string{
+		"HTTP/1.1 502 Bad Gateway",
+		"X-Upstream: backend-2",
+		"Set-Cookie: <redacted>",
+		"upstream timed out",
+		"connected to: " + strings.TrimPrefix(server.URL, "http://"),
+	} {
+		if !strings.Contains(bundle, expected) {
+			t.Errorf("expected %q in diagnostics:\n%s", expected, bundle)
+		}
+	}
+	if strings.Contains(bundle, "session=secret") {
+		t.Error("cookies must be redacted")
+	}
+}
+
+func TestFormatDiagnosticsConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	endpoint := server.URL
+	server.Close()
+
+	var report CheckReport
+	request := &EndpointRequest{Endpoint: endpoint}
+	err := checkLiveliness(http.DefaultClient, request, time.Second, &report)
+	if err == nil {
+		t.Fatal("expected closed server to fail")
+	}
+
+	bundle := formatDiagnostics(request, err, time.Now(), &report)
+	for _, expected := range []string{"*net.OpError", "operation: dial tcp", "errno:", "is an ip address"} {
+		if !strings.Contains(bundle, expected) {
+			t.Errorf("expected %q in diagnostics:\n%s", expected, bundle)
+		}
+	}
+}
+
+func TestDiagnosticsCaptionIsValidUtf8(t *testing.T) {
+	message := strings.Repeat("ошибка ", telegramCaptionLength)
+	document := diagnosticsDocument(message, "bundle")
+
+	if len(document.Caption) > telegramCaptionLength {
+		t.Errorf("expected caption within %d bytes, got %d", telegramCaptionLength, len(document.Caption))
+	}
+	if !utf8.ValidString(document.Caption) {
+		t.Error("expected caption to be cut on a rune boundary")
+	}
+}
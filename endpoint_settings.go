@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// parseEndpointOptions
+// Fills endpoint settings from the command arguments
+// written as key=value, e.g. /add ping://host pingCount=10 maxRtt=150ms
+// Option with empty value, e.g. interval=, is reset
+//
+// Options are converted into a yaml document so the same field names
+// and value formats are accepted as in the stored settings
+func parseEndpointOptions(request *EndpointRequest, options []string) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, option := range options {
+		key, value, found := strings.Cut(option, "=")
+		if !found || len(key) == 0 {
+			return fmt.Errorf("option %s must be written as key=value", option)
+		}
+		if len(value) == 0 {
+			if err := resetEndpointOption(request, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		mapping.Content = append(
+			mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+		)
+	}
+
+	optionsContent, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+
+	endpoint := request.Endpoint
+	err = decodeEndpointSettings(string(optionsContent), request)
+	request.Endpoint = endpoint
+
+	return err
+}
+
+// resetEndpointOption
+// Only stored settings can be reset, check state and credentials
+// are not settings even when their tag matches the key
+func resetEndpointOption(request *EndpointRequest, key string) error {
+	settings := reflect.ValueOf(request).Elem()
+	for i := 0; i < settings.NumField(); i++ {
+		field := settings.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "-" || name == "endpoint" {
+			continue
+		}
+		if name == key {
+			settings.Field(i).SetZero()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown option %s", key)
+}
+
+func encodeEndpointSettings(request *EndpointRequest) (string, error) {
+	settings, err := yaml.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	return string(settings), nil
+}
+
+func decodeEndpointSettings(settings string, request *EndpointRequest) error {
+	decoder := yaml.NewDecoder(strings.NewReader(settings))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(request)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
+
+// validateEndpointSettings
+// Catches settings that would fail every check
+// before they are stored
+func validateEndpointSettings(request *EndpointRequest) error {
+	if request.Interval != 0 && (request.Interval < minCheckInterval || request.Interval > maxCheckInterval) {
+		return fmt.Errorf("interval must be from %s to %s", minCheckInterval, maxCheckInterval)
+	}
+
+	if err := validateFailurePolicy(request); err != nil {
+		return err
+	}
+
+	if err := validateFlapping(request); err != nil {
+		return err
+	}
+
+	if len(request.Cron) > 0 {
+		if request.Interval != 0 {
+			return errors.New("interval and cron can't be used together")
+		}
+
+		schedule, err := ParseCron(request.Cron)
+		if err != nil {
+			return err
+		}
+		request.cronSchedule = schedule
+	}
+
+	if len(request.Expect) > 0 {
+		if _, err := regexp.Compile(request.Expect); err != nil {
+			return fmt.Errorf("invalid expect pattern: %w", err)
+		}
+	}
+
+	switch request.Crawl {
+	case "", crawlModeSitemap, crawlModeLinks:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCrawlMode, request.Crawl)
+	}
+
+	if len(request.JsonSchema) > 0 {
+		schema, err := compileJsonSchema(request.JsonSchema)
+		if err != nil {
+			return err
+		}
+		request.compiledSchema = schema
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResetEndpointOption(t *testing.T) {
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Minute, Retries: 3}
+	if err := parseEndpointOptions(request, []string{"interval=", "retries="}); err != nil {
+		t.Fatal(err)
+	}
+	if request.Interval != 0 || request.Retries != 0 {
+		t.Errorf("expected options to be reset, got interval %s and %d retries", request.Interval, request.Retries)
+	}
+
+	for _, option := range []string{"-=", "endpoint=", "unknown="} {
+		if err := parseEndpointOptions(request, []string{option}); err == nil {
+			t.Errorf("expected %s to be rejected", option)
+		}
+	}
+	if request.Endpoint != "https://api.com" {
+		t.Errorf("expected endpoint to be kept, got %s", request.Endpoint)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+type (
+	ErrorCategory string
+
+	// StatusCodeError
+	// Response status is not one of the expected ones
+	StatusCodeError struct {
+		Received int
+		Expected []int
+	}
+
+	// AssertionError
+	// Endpoint replied, but the reply does not match
+	// expect pattern, json schema, xpath or other configured assertion
+	AssertionError struct {
+		Err error
+	}
+
+	categoryTemplate struct {
+		emoji    string
+		severity string
+		title    string
+	}
+)
+
+const (
+	CategoryDns               ErrorCategory = "dns"
+	CategoryConnectionRefused ErrorCategory = "refused"
+	CategoryTimeout           ErrorCategory = "timeout"
+	CategoryTls               ErrorCategory = "tls"
+	CategoryHttp4xx           ErrorCategory = "http4xx"
+	CategoryHttp5xx           ErrorCategory = "http5xx"
+	CategoryAssertion         ErrorCategory = "assertion"
+	CategoryReachable         ErrorCategory = "reachable"
+	CategoryOther             ErrorCategory = "other"
+)
+
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+)
+
+var (
+	ErrUnknownCategory = errors.New("unknown error category")
+
+	errorCategories = []ErrorCategory{
+		CategoryDns,
+		CategoryConnectionRefused,
+		CategoryTimeout,
+		CategoryTls,
+		CategoryHttp4xx,
+		CategoryHttp5xx,
+		CategoryAssertion,
+		CategoryReachable,
+		CategoryOther,
+	}
+
+	categoryTemplates = map[ErrorCategory]categoryTemplate{
+		CategoryDns:               {emoji: "🔴", severity: severityCritical, title: "DNS lookup failed"},
+		CategoryConnectionRefused: {emoji: "🔴", severity: severityCritical, title: "Connection refused"},
+		CategoryTimeout:           {emoji: "🔴", severity: severityCritical, title: "Timed out"},
+		CategoryTls:               {emoji: "🔒", severity: severityCritical, title: "TLS error"},
+		CategoryHttp4xx:           {emoji: "🟠", severity: severityWarning, title: "Client error response"},
+		CategoryHttp5xx:           {emoji: "🔴", severity: severityCritical, title: "Server error response"},
+		CategoryAssertion:         {emoji: "🟡", severity: severityWarning, title: "Assertion failed"},
+		CategoryReachable:         {emoji: "🟠", severity: severityWarning, title: "Unexpectedly reachable"},
+		CategoryOther:             {emoji: "🔴", severity: severityCritical, title: "Check failed"},
+	}
+)
+
+func (e *StatusCodeError) Error() string {
+	expected := make([]string, len(e.Expected))
+	for i, status := range e.Expected {
+		expected[i] = fmt.Sprint(status)
+	}
+
+	return fmt.Sprintf("Invalid status code. Received: %d, expected: %s", e.Received, strings.Join(expected, " or "))
+}
+
+func (e *AssertionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AssertionError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError
+// Order matters: tls and dns errors are wrapped into *url.Error
+// and *net.OpError, which are also returned for timeouts
+func classifyError(err error) ErrorCategory {
+	var (
+		statusErr    *StatusCodeError
+		assertionErr *AssertionError
+		dnsErr       *net.DNSError
+		netErr       net.Error
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrUnexpectedlyReachable):
+		return CategoryReachable
+	case errors.As(err, &statusErr):
+		return classifyStatusCode(statusErr.Received)
+	case errors.As(err, &assertionErr):
+		return CategoryAssertion
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout && !dnsErr.IsNotFound {
+			return CategoryTimeout
+		}
+		return CategoryDns
+	case isTlsError(err):
+		return CategoryTls
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CategoryConnectionRefused
+	}
+
+	return CategoryOther
+}
+
+func classifyStatusCode(status int) ErrorCategory {
+	switch {
+	case status >= 500:
+		return CategoryHttp5xx
+	case status >= 400:
+		return CategoryHttp4xx
+	}
+
+	return CategoryAssertion
+}
+
+func isTlsError(err error) bool {
+	var (
+		hostnameErr     x509.HostnameError
+		invalidErr      x509.CertificateInvalidError
+		authorityErr    x509.UnknownAuthorityError
+		verificationErr *tls.CertificateVerificationError
+		recordErr       tls.RecordHeaderError
+		alertErr        tls.AlertError
+	)
+
+	return errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &verificationErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr)
+}
+
+// alertMessage
+// Url errors repeat the method and the endpoint,
+// so only the innermost cause is shown as details
+func alertMessage(endpoint string, err error, category ErrorCategory) string {
+	template, ok := categoryTemplates[category]
+	if !ok {
+		template = categoryTemplates[CategoryOther]
+	}
+
+	if category == CategoryReachable {
+		return fmt.Sprintf("%s %s: endpoint %s is unexpectedly reachable", template.emoji, template.title, endpoint)
+	}
+
+	details := err.Error()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		details = urlErr.Err.Error()
+	}
+
+	return fmt.Sprintf(
+		"%s %s [%s]\nendpoint: %s\ndetails: %s",
+		template.emoji,
+		template.title,
+		template.severity,
+		endpoint,
+		details,
+	)
+}
+
+// parseErrorCategories
+// Accepts comma separated list of categories,
+// "all" clears the filter
+func parseErrorCategories(value string) ([]ErrorCategory, error) {
+	if value == "all" {
+		return nil, nil
+	}
+
+	var categories []ErrorCategory
+	for _, name := range strings.Split(value, ",") {
+		category := ErrorCategory(strings.TrimSpace(name))
+		if !slices.Contains(errorCategories, category) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCategory, category)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func encodeErrorCategories(categories []ErrorCategory) string {
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = string(category)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// categoryAllowed
+// Empty filter allows every category
+func categoryAllowed(filter string, category ErrorCategory) bool {
+	if len(filter) == 0 {
+		return true
+	}
+
+	return slices.Contains(strings.Split(filter, ","), string(category))
+}
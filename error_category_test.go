@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedUrl := closed.URL
+	closed.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+
+	selfSigned := httptest.NewTLSServer(http.NotFoundHandler())
+	defer selfSigned.Close()
+
+	statuses := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("v1"))
+		}
+	}))
+	defer statuses.Close()
+
+	cases := []struct {
+		request  EndpointRequest
+		timeout  time.Duration
+		category ErrorCategory
+	}{
+		{request: EndpointRequest{Endpoint: closedUrl}, category: CategoryConnectionRefused},
+		{request: EndpointRequest{Endpoint: slow.URL}, timeout: 50 * time.Millisecond, category: CategoryTimeout},
+		{request: EndpointRequest{Endpoint: selfSigned.URL}, category: CategoryTls},
+		{request: EndpointRequest{Endpoint: statuses.URL + "/missing"}, category: CategoryHttp4xx},
+		{request: EndpointRequest{Endpoint: statuses.URL + "/broken"}, category: CategoryHttp5xx},
+		{request: EndpointRequest{Endpoint: statuses.URL, Expect: "^v2"}, category: CategoryAssertion},
+		{request: EndpointRequest{Endpoint: "http://monitor-test.invalid"}, category: CategoryDns},
+	}
+
+	for _, c := range cases {
+		timeout := c.timeout
+		if timeout == 0 {
+			timeout = 2 * time.Second
+		}
+
+		var report CheckReport
+		err := checkLiveliness(http.DefaultClient, &c.request, timeout, &report)
+		if category := classifyError(err); category != c.category {
+			t.Errorf("%s: expected %s, got %s (%v)", c.request.Endpoint, c.category, category, err)
+		}
+	}
+
+	if category := classifyError(context.DeadlineExceeded); category != CategoryTimeout {
+		t.Errorf("expected deadline to be timeout, got %s", category)
+	}
+	if category := classifyError(tls.AlertError(40)); category != CategoryTls {
+		t.Errorf("expected tls alert to be tls, got %s", category)
+	}
+	if category := classifyError(ErrUnexpectedlyReachable); category != CategoryReachable {
+		t.Errorf("expected reachable, got %s", category)
+	}
+}
+
+func TestAlertMessage(t *testing.T) {
+	message := alertMessage("https://api.com", &StatusCodeError{Received: 503, Expected: []int{200}}, CategoryHttp5xx)
+	if !strings.HasPrefix(message, "🔴 Server error response [critical]") {
+		t.Errorf("unexpected message %q", message)
+	}
+	if !strings.Contains(message, "Received: 503, expected: 200") {
+		t.Errorf("expected status details in %q", message)
+	}
+}
+
+func TestParseErrorCategories(t *testing.T) {
+	categories, err := parseErrorCategories("dns,http5xx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := encodeErrorCategories(categories)
+	if !categoryAllowed(filter, CategoryDns) || categoryAllowed(filter, CategoryHttp4xx) {
+		t.Errorf("unexpected filter %s", filter)
+	}
+
+	if _, err := parseErrorCategories("dns,slow"); err == nil {
+		t.Error("expected unknown category to fail")
+	}
+
+	categories, err = parseErrorCategories("all")
+	if err != nil || !categoryAllowed(encodeErrorCategories(categories), CategoryOther) {
+		t.Error("all must clear the filter")
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// EscalationPolicy
+	// Alert of the matching endpoint is sent to the chats of the steps
+	// one after another until somebody acknowledges the incident.
+	// Delay of the step is counted from the previous step,
+	// target is an endpoint, tag:name or all like in maintenance windows
+	EscalationPolicy struct {
+		Id     int64
+		Name   string
+		Target string
+		Steps  []EscalationStep
+	}
+
+	EscalationStep struct {
+		ChatId int64
+		Delay  time.Duration
+	}
+)
+
+const (
+	escalationTickInterval = 15 * time.Second
+	maxEscalationSteps     = 10
+	minEscalationDelay     = time.Minute
+)
+
+var (
+	ErrInvalidEscalation = errors.New("invalid escalation policy")
+)
+
+// escalationWakeup
+// New escalation is started without waiting for the next tick,
+// so the first step is not delayed
+var escalationWakeup = make(chan struct{}, 1)
+
+func (p EscalationPolicy) String() string {
+	var steps strings.Builder
+	for i, step := range p.Steps {
+		if i > 0 || step.Delay > 0 {
+			steps.WriteString(fmt.Sprintf(" → %s →", step.Delay))
+		}
+		steps.WriteString(fmt.Sprintf(" %d", step.ChatId))
+	}
+
+	return fmt.Sprintf("%s for %s:%s", p.Name, p.Target, steps.String())
+}
+
+// parseEscalationSteps
+// Parses chats and delays between them: chat [delay chat ...],
+// optional leading delay postpones the first chat.
+// Chat is a telegram chat id or me for the sender
+func parseEscalationSteps(args []string, senderId int64) ([]EscalationStep, error) {
+	var (
+		steps []EscalationStep
+		delay time.Duration
+	)
+	expectChat := false
+	for i, arg := range args {
+		if !expectChat {
+			if parsed, err := time.ParseDuration(arg); err == nil {
+				if parsed < minEscalationDelay || parsed > maxCheckInterval {
+					return nil, fmt.Errorf("%w: delay must be from %s to %s", ErrInvalidEscalation, minEscalationDelay, maxCheckInterval)
+				}
+				delay = parsed
+				expectChat = true
+				continue
+			}
+			if i > 0 {
+				return nil, fmt.Errorf("%w: expected delay before %s", ErrInvalidEscalation, arg)
+			}
+		}
+
+		chatId := senderId
+		if arg != "me" {
+			parsed, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || parsed == 0 {
+				return nil, fmt.Errorf("%w: invalid chat %s", ErrInvalidEscalation, arg)
+			}
+			chatId = parsed
+		}
+
+		steps = append(steps, EscalationStep{ChatId: chatId, Delay: delay})
+		delay = 0
+		expectChat = false
+	}
+
+	if expectChat {
+		return nil, fmt.Errorf("%w: delay without chat", ErrInvalidEscalation)
+	}
+	if len(steps) == 0 || len(steps) > maxEscalationSteps {
+		return nil, fmt.Errorf("%w: policy must have from 1 to %d chats", ErrInvalidEscalation, maxEscalationSteps)
+	}
+
+	return steps, nil
+}
+
+// verifyEscalationChats
+// Policy sends alerts only to the sender and to the chats
+// the sender is a member of
+func verifyEscalationChats(bot *tele.Bot, sender *tele.User, steps []EscalationStep) error {
+	for _, step := range steps {
+		if step.ChatId == sender.ID {
+			continue
+		}
+
+		member, err := bot.ChatMemberOf(&tele.Chat{ID: step.ChatId}, sender)
+		if err != nil || !isChatMember(member) {
+			return fmt.Errorf("%w: you are not a member of chat %d", ErrInvalidEscalation, step.ChatId)
+		}
+	}
+
+	return nil
+}
+
+func isChatMember(member *tele.ChatMember) bool {
+	switch member.Role {
+	case tele.Creator, tele.Administrator, tele.Member:
+		return true
+	case tele.Restricted:
+		return member.Member
+	}
+
+	return false
+}
+
+// saveEscalationPolicy
+// Policy with the same name is replaced, escalations that are
+// in progress continue with the new steps
+func saveEscalationPolicy(ctx context.Context, clientId int64, policy EscalationPolicy) (int64, error) {
+	var id int64
+	err := inTransaction(ctx, func(q *monitor_db.Queries) error {
+		var err error
+		id, err = q.AddEscalationPolicy(ctx, monitor_db.AddEscalationPolicyParams{
+			Clientid: clientId,
+			Name:     policy.Name,
+			Target:   policy.Target,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.RemoveEscalationSteps(ctx, id); err != nil {
+			return err
+		}
+		for position, step := range policy.Steps {
+			err := q.AddEscalationStep(ctx, monitor_db.AddEscalationStepParams{
+				Policyid:     id,
+				Position:     int64(position),
+				Chatid:       step.ChatId,
+				Delayseconds: int64(step.Delay / time.Second),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return id, err
+}
+
+func removeEscalationPolicy(ctx context.Context, clientId int64, name string) error {
+	return inTransaction(ctx, func(q *monitor_db.Queries) error {
+		id, err := q.GetEscalationPolicyId(ctx, monitor_db.GetEscalationPolicyIdParams{
+			Clientid: clientId,
+			Name:     name,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.RemovePolicyEscalations(ctx, id); err != nil {
+			return err
+		}
+		if err := q.RemoveEscalationSteps(ctx, id); err != nil {
+			return err
+		}
+
+		return q.RemoveEscalationPolicy(ctx, id)
+	})
+}
+
+func clientEscalationPolicies(ctx context.Context, clientId int64) ([]EscalationPolicy, error) {
+	rows, err := botStorage.q.GetClientEscalationPolicies(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []EscalationPolicy
+	for _, row := range rows {
+		if len(policies) == 0 || policies[len(policies)-1].Id != row.ID {
+			policies = append(policies, EscalationPolicy{Id: row.ID, Name: row.Name, Target: row.Target})
+		}
+		policy := &policies[len(policies)-1]
+		policy.Steps = append(policy.Steps, EscalationStep{
+			ChatId: row.Chatid,
+			Delay:  time.Duration(row.Delayseconds) * time.Second,
+		})
+	}
+
+	return policies, nil
+}
+
+// startEscalations
+// Every policy of the endpoint subscribers escalates the incident on its own.
+// Escalation that already exists is kept, so alert repeated
+// after restart does not start the policy over
+func startEscalations(ctx context.Context, q *monitor_db.Queries, incidentId int64, endpoint string, now time.Time) error {
+	policies, err := q.GetEndpointEscalationPolicies(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	tags := endpointTags(endpoint)
+	started := false
+	for _, policy := range policies {
+		if !(MaintenanceWindow{Target: policy.Target}).Matches(endpoint, tags) {
+			continue
+		}
+
+		silenced, err := inMaintenance(ctx, q, policy.Clientid, endpoint, now)
+		if err != nil {
+			log.Error().Int64("client", policy.Clientid).Err(err).Msg("check maintenance windows")
+		}
+		if silenced {
+			continue
+		}
+
+		steps, err := q.GetEscalationSteps(ctx, policy.ID)
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			continue
+		}
+
+		err = q.StartEscalation(ctx, monitor_db.StartEscalationParams{
+			Incidentid: incidentId,
+			Policyid:   policy.ID,
+			Escalateat: now.Add(time.Duration(steps[0].Delayseconds) * time.Second),
+		})
+		if err != nil {
+			return err
+		}
+		started = true
+	}
+
+	if started {
+		select {
+		case escalationWakeup <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// RunEscalations
+// Escalation state and the time of its next step are stored in the database,
+// so escalations are continued after restart.
+// Escalation ends when the incident is acknowledged or resolved,
+// or when the last step of the policy is notified.
+// It waits while the owner of the policy has the endpoint paused
+func RunEscalations(ctx context.Context, bot *tele.Bot) {
+	ticker := time.NewTicker(escalationTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-escalationWakeup:
+		}
+
+		advanceEscalations(ctx, bot, time.Now())
+	}
+}
+
+func advanceEscalations(ctx context.Context, bot *tele.Bot, now time.Time) {
+	escalations, err := botStorage.q.GetEscalations(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("load escalations")
+		return
+	}
+
+	for _, escalation := range escalations {
+		if escalation.Ackedat.Valid || escalation.Resolvedat.Valid {
+			stopEscalation(ctx, escalation)
+			continue
+		}
+		if now.Before(escalation.Escalateat) {
+			continue
+		}
+
+		steps, err := botStorage.q.GetEscalationSteps(ctx, escalation.Policyid)
+		if err != nil {
+			log.Error().Int64("policy", escalation.Policyid).Err(err).Msg("load escalation steps")
+			continue
+		}
+		step := int(escalation.Step)
+		if step >= len(steps) {
+			stopEscalation(ctx, escalation)
+			continue
+		}
+
+		sendEscalation(ctx, bot, escalation, steps[step].Chatid, len(steps), now)
+
+		if step+1 == len(steps) {
+			stopEscalation(ctx, escalation)
+			continue
+		}
+		err = botStorage.q.AdvanceEscalation(ctx, monitor_db.AdvanceEscalationParams{
+			Step:       int64(step + 1),
+			Escalateat: now.Add(time.Duration(steps[step+1].Delayseconds) * time.Second),
+			Incidentid: escalation.Incidentid,
+			Policyid:   escalation.Policyid,
+		})
+		if err != nil {
+			log.Error().Int64("incident", escalation.Incidentid).Err(err).Msg("advance escalation")
+		}
+	}
+}
+
+// sendEscalation
+// Escalated alert has the incident buttons and is recorded as incident message,
+// so it can be acknowledged from the chat and gets the recovery.
+// Chat that already got the alert keeps it as the incident message,
+// with its reminders, snooze and mute
+func sendEscalation(ctx context.Context, bot *tele.Bot, escalation monitor_db.GetEscalationsRow, chatId int64, steps int, now time.Time) {
+	chat := &tele.Chat{ID: chatId}
+	sent, err := bot.Send(chat, escalationMessage(escalation, steps, now), incidentMarkup(escalation.Incidentid))
+	if err != nil {
+		log.Error().Int64("chat", chatId).Err(err).Msg("send escalation")
+		return
+	}
+
+	content, isDocument := messageContent(sent)
+	err = botStorage.q.AddEscalationMessage(ctx, monitor_db.AddEscalationMessageParams{
+		Incidentid: escalation.Incidentid,
+		Chatid:     chatId,
+		Messageid:  int64(sent.ID),
+		Notifiedat: sql.NullTime{Time: now, Valid: true},
+		Content:    content,
+		Document:   isDocument,
+	})
+	if err != nil {
+		log.Error().Int64("incident", escalation.Incidentid).Err(err).Msg("record escalation message")
+	}
+}
+
+func escalationMessage(escalation monitor_db.GetEscalationsRow, steps int, now time.Time) string {
+	return fmt.Sprintf(
+		"📣 %s is down for %s and nobody acknowledged it\nerror: %s\nescalation %s, step %d of %d",
+		escalation.Url,
+		now.Sub(escalation.Startedat).Round(time.Minute),
+		escalation.Error,
+		escalation.Name,
+		escalation.Step+1,
+		steps,
+	)
+}
+
+func stopEscalation(ctx context.Context, escalation monitor_db.GetEscalationsRow) {
+	err := botStorage.q.StopEscalation(ctx, monitor_db.StopEscalationParams{
+		Incidentid: escalation.Incidentid,
+		Policyid:   escalation.Policyid,
+	})
+	if err != nil {
+		log.Error().Int64("incident", escalation.Incidentid).Err(err).Msg("stop escalation")
+	}
+}
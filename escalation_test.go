@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"testing"
+	"time"
+)
+
+func TestParseEscalationSteps(t *testing.T) {
+	steps, err := parseEscalationSteps([]string{"me", "15m", "42", "30m", "-100123"}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []EscalationStep{{ChatId: 7}, {ChatId: 42, Delay: 15 * time.Minute}, {ChatId: -100123, Delay: 30 * time.Minute}}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %+v", len(expected), steps)
+	}
+	for i := range expected {
+		if steps[i] != expected[i] {
+			t.Errorf("step %d: expected %+v, got %+v", i, expected[i], steps[i])
+		}
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"me", "15m"},
+		{"me", "42"},
+		{"me", "10s", "42"},
+		{"chat"},
+	} {
+		if _, err := parseEscalationSteps(args, 7); !errors.Is(err, ErrInvalidEscalation) {
+			t.Errorf("expected %v to be invalid, got %v", args, err)
+		}
+	}
+}
+
+func TestEscalationSurvivesRestartAndStopsOnAck(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	_, err := saveEscalationPolicy(ctx, 1, EscalationPolicy{
+		Name:   "oncall",
+		Target: maintenanceTargetAll,
+		Steps:  []EscalationStep{{ChatId: 1, Delay: 5 * time.Minute}, {ChatId: 2, Delay: 15 * time.Minute}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// policy of the client who is not subscribed is not applied
+	_, err = saveEscalationPolicy(ctx, 3, EscalationPolicy{
+		Name:   "other",
+		Target: maintenanceTargetAll,
+		Steps:  []EscalationStep{{ChatId: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downAt := time.Now()
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+		DownSince:       downAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := startEscalations(ctx, botStorage.q, incidentId, endpoint, downAt); err != nil {
+		t.Fatal(err)
+	}
+	// alert repeated after restart keeps the escalation timer
+	if err := startEscalations(ctx, botStorage.q, incidentId, endpoint, downAt.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	escalations, err := botStorage.q.GetEscalations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(escalations) != 1 || escalations[0].Name != "oncall" {
+		t.Fatalf("expected only escalation of the subscriber, got %+v", escalations)
+	}
+	if !escalations[0].Escalateat.Equal(downAt.Add(5 * time.Minute)) {
+		t.Errorf("expected first step after its delay, got %s", escalations[0].Escalateat)
+	}
+
+	// step is not due yet, nothing is sent
+	advanceEscalations(ctx, nil, downAt.Add(time.Minute))
+	if escalations, _ := botStorage.q.GetEscalations(ctx); len(escalations) != 1 || escalations[0].Step != 0 {
+		t.Fatalf("expected escalation to wait for its step, got %+v", escalations)
+	}
+
+	_, err = botStorage.q.AcknowledgeIncident(ctx, monitor_db.AcknowledgeIncidentParams{
+		Ackedby:   "@oncall",
+		Ackedbyid: 1,
+		Ackedat:   sql.NullTime{Time: time.Now(), Valid: true},
+		ID:        incidentId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	advanceEscalations(ctx, nil, downAt.Add(time.Hour))
+	if escalations, _ := botStorage.q.GetEscalations(ctx); len(escalations) != 0 {
+		t.Errorf("expected acknowledged incident to stop escalation, got %+v", escalations)
+	}
+}
+
+func TestRemovedPolicyStopsEscalations(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	_, err := saveEscalationPolicy(ctx, 1, EscalationPolicy{
+		Name:   "oncall",
+		Target: endpoint,
+		Steps:  []EscalationStep{{ChatId: 2, Delay: time.Hour}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := startEscalations(ctx, botStorage.q, incidentId, endpoint, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeEscalationPolicy(ctx, 1, "oncall"); err != nil {
+		t.Fatal(err)
+	}
+	if escalations, _ := botStorage.q.GetEscalations(ctx); len(escalations) != 0 {
+		t.Errorf("expected escalations of removed policy to stop, got %+v", escalations)
+	}
+	if policies, _ := clientEscalationPolicies(ctx, 1); len(policies) != 0 {
+		t.Errorf("expected policy to be removed, got %+v", policies)
+	}
+	if err := removeEscalationPolicy(ctx, 1, "oncall"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected missing policy error, got %v", err)
+	}
+}
+
+func TestEscalationKeepsAlertMessage(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifiedAt := sql.NullTime{Time: time.Now(), Valid: true}
+	err = botStorage.q.AddIncidentMessage(ctx, monitor_db.AddIncidentMessageParams{
+		Incidentid: incidentId,
+		Chatid:     1,
+		Messageid:  10,
+		Notifiedat: notifiedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := botStorage.q.MuteIncidentMessage(ctx, monitor_db.MuteIncidentMessageParams{Incidentid: incidentId, Chatid: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chatId := range []int64{1, 2} {
+		err := botStorage.q.AddEscalationMessage(ctx, monitor_db.AddEscalationMessageParams{
+			Incidentid: incidentId,
+			Chatid:     chatId,
+			Messageid:  20,
+			Notifiedat: notifiedAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err := botStorage.q.GetIncidentMessages(ctx, incidentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[int64]int64{1: 10, 2: 20}
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %d incident messages, got %+v", len(expected), messages)
+	}
+	for _, message := range messages {
+		if expected[message.Chatid] != message.Messageid {
+			t.Errorf("chat %d: expected message %d, got %d", message.Chatid, expected[message.Chatid], message.Messageid)
+		}
+	}
+
+	var muted bool
+	if err := botStorage.db.QueryRow("select muted from incident_messages where chatId = 1").Scan(&muted); err != nil {
+		t.Fatal(err)
+	}
+	if !muted {
+		t.Error("expected escalation to keep the alert muted")
+	}
+}
+
+func TestPausedSubscriptionHoldsEscalation(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	_, err := saveEscalationPolicy(ctx, 1, EscalationPolicy{
+		Name:   "oncall",
+		Target: endpoint,
+		Steps:  []EscalationStep{{ChatId: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := startEscalations(ctx, botStorage.q, incidentId, endpoint, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setSubscriptionPaused(ctx, 1, endpoint, true); err != nil {
+		t.Fatal(err)
+	}
+	if escalations, _ := botStorage.q.GetEscalations(ctx); len(escalations) != 0 {
+		t.Fatalf("expected escalation of paused endpoint to wait, got %+v", escalations)
+	}
+
+	if err := setSubscriptionPaused(ctx, 1, endpoint, false); err != nil {
+		t.Fatal(err)
+	}
+	if escalations, _ := botStorage.q.GetEscalations(ctx); len(escalations) != 1 {
+		t.Errorf("expected escalation of resumed endpoint, got %+v", escalations)
+	}
+}
+
+func TestEscalationChatMember(t *testing.T) {
+	tests := []struct {
+		member   tele.ChatMember
+		expected bool
+	}{
+		{tele.ChatMember{Role: tele.Creator}, true},
+		{tele.ChatMember{Role: tele.Member}, true},
+		{tele.ChatMember{Role: tele.Restricted, Member: true}, true},
+		{tele.ChatMember{Role: tele.Restricted}, false},
+		{tele.ChatMember{Role: tele.Left}, false},
+		{tele.ChatMember{Role: tele.Kicked}, false},
+	}
+
+	for _, test := range tests {
+		if isChatMember(&test.member) != test.expected {
+			t.Errorf("%s: expected member to be %t", test.member.Role, test.expected)
+		}
+	}
+}
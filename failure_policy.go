@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultRetryInterval = 10 * time.Second
+	minRetryInterval     = time.Second
+	maxRetries           = 10
+	maxCheckThreshold    = 100
+)
+
+// trackCheck
+// Counts consecutive results of the checks, so a single blip does not alert.
+// Failure is confirmed by retries, then the endpoint goes down after
+// alertAfter failed checks or after failing for alertAfterTime,
+// whichever comes first, and recovers after recoverAfter passed checks
+func (r *EndpointRequest) trackCheck(err error, checkedAt time.Time) (wentDown, recovered bool) {
+	if err != nil {
+		if r.failedChecks == 0 {
+			r.failingSince = checkedAt
+		}
+		r.failedChecks++
+		r.passedChecks = 0
+
+		if r.requestError != nil || !r.failureConfirmed(checkedAt) {
+			return false, false
+		}
+		r.requestError = err
+		return true, false
+	}
+
+	if r.requestError != nil {
+		r.passedChecks++
+		if r.passedChecks < max(r.RecoverAfter, 1) {
+			return false, false
+		}
+		recovered = true
+	}
+
+	r.requestError = nil
+	r.failedChecks = 0
+	r.passedChecks = 0
+	r.failingSince = time.Time{}
+
+	return false, recovered
+}
+
+// restoreDown
+// Brings back the state of the endpoint that was down before restart
+func (r *EndpointRequest) restoreDown(err error, since time.Time, failedChecks int) {
+	r.requestError = err
+	r.failingSince = since
+	r.failedChecks = max(failedChecks, r.Retries+1, 1)
+}
+
+func (r *EndpointRequest) failureConfirmed(now time.Time) bool {
+	if r.failedChecks <= r.Retries {
+		return false
+	}
+
+	if r.AlertAfterTime > 0 && now.Sub(r.failingSince) >= r.AlertAfterTime {
+		return true
+	}
+	if r.AlertAfter > 0 {
+		return r.failedChecks >= r.AlertAfter
+	}
+
+	return r.AlertAfterTime == 0
+}
+
+// followUpDelay
+// Failed check is retried quickly until retries confirm it,
+// then the down endpoint is checked every downInterval,
+// growing by downBackoff after every failed check.
+// Zero delay keeps the regular interval
+func followUpDelay(r *EndpointRequest) time.Duration {
+	// recovering endpoint is checked at the regular interval
+	if r.failedChecks == 0 || r.passedChecks > 0 {
+		return 0
+	}
+
+	if r.failedChecks <= r.Retries {
+		if r.RetryInterval > 0 {
+			return r.RetryInterval
+		}
+		return defaultRetryInterval
+	}
+
+	if r.DownInterval <= 0 {
+		return 0
+	}
+
+	delay := r.DownInterval
+	if r.DownBackoff > 1 {
+		backoff := math.Pow(r.DownBackoff, float64(r.failedChecks-r.Retries-1))
+		delay = time.Duration(math.Min(float64(r.DownInterval)*backoff, float64(maxCheckInterval)))
+	}
+
+	maxDelay := r.MaxDownInterval
+	if maxDelay <= 0 {
+		maxDelay = maxCheckInterval
+	}
+
+	return min(delay, maxDelay)
+}
+
+func validateFailurePolicy(r *EndpointRequest) error {
+	if r.Retries < 0 || r.Retries > maxRetries {
+		return fmt.Errorf("retries must be from 0 to %d", maxRetries)
+	}
+
+	if r.RetryInterval != 0 && (r.RetryInterval < minRetryInterval || r.RetryInterval > maxCheckInterval) {
+		return fmt.Errorf("retryInterval must be from %s to %s", minRetryInterval, maxCheckInterval)
+	}
+
+	if r.DownInterval != 0 && (r.DownInterval < minCheckInterval || r.DownInterval > maxCheckInterval) {
+		return fmt.Errorf("downInterval must be from %s to %s", minCheckInterval, maxCheckInterval)
+	}
+
+	if r.DownBackoff != 0 && r.DownBackoff < 1 {
+		return errors.New("downBackoff must be at least 1")
+	}
+
+	if r.MaxDownInterval != 0 && r.MaxDownInterval < r.DownInterval {
+		return errors.New("maxDownInterval must not be less than downInterval")
+	}
+
+	if r.AlertAfter < 0 || r.AlertAfter > maxCheckThreshold {
+		return fmt.Errorf("alertAfter must be from 0 to %d", maxCheckThreshold)
+	}
+
+	if r.AlertAfterTime != 0 && (r.AlertAfterTime < minCheckInterval || r.AlertAfterTime > maxCheckInterval) {
+		return fmt.Errorf("alertAfterTime must be from %s to %s", minCheckInterval, maxCheckInterval)
+	}
+
+	if r.RecoverAfter < 0 || r.RecoverAfter > maxCheckThreshold {
+		return fmt.Errorf("recoverAfter must be from 0 to %d", maxCheckThreshold)
+	}
+
+	return nil
+}
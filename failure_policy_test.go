@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFollowUpDelay(t *testing.T) {
+	request := &EndpointRequest{
+		Retries:         2,
+		DownInterval:    30 * time.Second,
+		DownBackoff:     2,
+		MaxDownInterval: 2 * time.Minute,
+	}
+
+	expected := []time.Duration{
+		0,
+		defaultRetryInterval,
+		defaultRetryInterval,
+		30 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		2 * time.Minute,
+	}
+	for failedChecks, delay := range expected {
+		request.failedChecks = failedChecks
+		if actual := followUpDelay(request); actual != delay {
+			t.Errorf("after %d failed checks expected %s, got %s", failedChecks, delay, actual)
+		}
+	}
+
+	request.DownInterval = 0
+	request.failedChecks = 5
+	if delay := followUpDelay(request); delay != 0 {
+		t.Errorf("expected regular interval while down without downInterval, got %s", delay)
+	}
+}
+
+func TestValidateFailurePolicy(t *testing.T) {
+	invalid := []EndpointRequest{
+		{Retries: -1},
+		{Retries: maxRetries + 1},
+		{RetryInterval: time.Millisecond},
+		{DownInterval: time.Second},
+		{DownBackoff: 0.5},
+		{DownInterval: time.Minute, MaxDownInterval: 30 * time.Second},
+		{AlertAfter: maxCheckThreshold + 1},
+		{AlertAfterTime: time.Second},
+		{RecoverAfter: -1},
+	}
+	for _, request := range invalid {
+		if err := validateFailurePolicy(&request); err == nil {
+			t.Errorf("expected %+v to be invalid", request)
+		}
+	}
+}
+
+func TestSchedulerReschedule(t *testing.T) {
+	s := NewScheduler(1, 0)
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	s.Add(request, true)
+	s.next(time.Now().Add(time.Millisecond))
+
+	s.Reschedule(request.Endpoint, 10*time.Second)
+	if _, wait := s.next(time.Now()); wait > 10*time.Second {
+		t.Fatalf("expected retry within 10s, got %s", wait)
+	}
+
+	retryAt := time.Now().Add(10 * time.Second)
+	if due, _ := s.next(retryAt); due != request {
+		t.Fatal("expected retry to be due")
+	}
+	if _, wait := s.next(retryAt); wait != nextSlot(request, retryAt).Sub(retryAt) {
+		t.Errorf("expected request to return to regular slot, got %s", wait)
+	}
+}
+
+func TestTrackCheckThresholds(t *testing.T) {
+	failure := errors.New("connection refused")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	request := &EndpointRequest{AlertAfter: 3, RecoverAfter: 2}
+	results := []error{failure, failure, nil, failure, failure, failure, failure, nil, failure, nil, nil}
+	var downAt, recoveredAt []int
+	for i, err := range results {
+		wentDown, recovered := request.trackCheck(err, start.Add(time.Duration(i)*time.Minute))
+		if wentDown {
+			downAt = append(downAt, i)
+		}
+		if recovered {
+			recoveredAt = append(recoveredAt, i)
+		}
+	}
+
+	if !slices.Equal(downAt, []int{5}) {
+		t.Errorf("expected to go down once after 3 failures in a row, got %v", downAt)
+	}
+	if !slices.Equal(recoveredAt, []int{10}) {
+		t.Errorf("expected to recover after 2 passed checks in a row, got %v", recoveredAt)
+	}
+	if request.requestError != nil || request.failedChecks != 0 {
+		t.Errorf("expected state to be reset after recovery, got %+v", request)
+	}
+}
+
+func TestTrackCheckFailingTime(t *testing.T) {
+	failure := errors.New("timeout")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	request := &EndpointRequest{AlertAfter: 100, AlertAfterTime: 5 * time.Minute}
+	for minute := 0; minute < 5; minute++ {
+		if wentDown, _ := request.trackCheck(failure, start.Add(time.Duration(minute)*time.Minute)); wentDown {
+			t.Fatalf("went down after %d minutes", minute)
+		}
+	}
+
+	if wentDown, _ := request.trackCheck(failure, start.Add(5*time.Minute)); !wentDown {
+		t.Error("expected to go down after failing for 5 minutes")
+	}
+	if request.requestError != failure {
+		t.Errorf("expected first confirmed error to be kept, got %v", request.requestError)
+	}
+}
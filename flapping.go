@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"time"
+)
+
+const (
+	defaultFlapTransitions = 5
+	defaultFlapWindow      = time.Hour
+	defaultFlapStable      = 30 * time.Minute
+	maxFlapTransitions     = 100
+)
+
+// trackFlapping
+// Endpoint is flapping when it changes state more than flapTransitions
+// times within flapWindow. Its state changes are not sent
+// until there were no changes for flapStable
+func (r *EndpointRequest) trackFlapping(transition bool, checkedAt time.Time) EndpointEvent {
+	threshold, window, stable := flapLimits(r)
+
+	if transition {
+		recent := r.transitions[:0]
+		for _, changedAt := range r.transitions {
+			if checkedAt.Sub(changedAt) < window {
+				recent = append(recent, changedAt)
+			}
+		}
+		r.transitions = append(recent, checkedAt)
+
+		if r.flapping {
+			r.suppressedTransitions++
+			return ""
+		}
+		if len(r.transitions) > threshold {
+			r.flapping = true
+			r.flappingSince = checkedAt
+			r.suppressedTransitions = 1
+			return EventFlapping
+		}
+		return ""
+	}
+
+	if !r.flapping || checkedAt.Sub(r.transitions[len(r.transitions)-1]) < stable {
+		return ""
+	}
+
+	r.flapping = false
+	r.transitions = nil
+
+	return EventStable
+}
+
+func flapLimits(r *EndpointRequest) (int, time.Duration, time.Duration) {
+	threshold, window, stable := r.FlapTransitions, r.FlapWindow, r.FlapStable
+	if threshold == 0 {
+		threshold = defaultFlapTransitions
+	}
+	if window == 0 {
+		window = defaultFlapWindow
+	}
+	if stable == 0 {
+		stable = defaultFlapStable
+	}
+
+	return threshold, window, stable
+}
+
+func validateFlapping(r *EndpointRequest) error {
+	if r.FlapTransitions < 0 || r.FlapTransitions > maxFlapTransitions {
+		return fmt.Errorf("flapTransitions must be from 0 to %d", maxFlapTransitions)
+	}
+
+	if r.FlapWindow != 0 && (r.FlapWindow < minCheckInterval || r.FlapWindow > maxCheckInterval) {
+		return fmt.Errorf("flapWindow must be from %s to %s", minCheckInterval, maxCheckInterval)
+	}
+
+	if r.FlapStable != 0 && (r.FlapStable < minCheckInterval || r.FlapStable > maxCheckInterval) {
+		return fmt.Errorf("flapStable must be from %s to %s", minCheckInterval, maxCheckInterval)
+	}
+
+	return nil
+}
+
+func flappingMessage(event RequestError, now time.Time) string {
+	threshold, window, stable := flapLimits(&event.EndpointRequest)
+	if event.Event == EventFlapping {
+		return fmt.Sprintf(
+			"🔁 %s is flapping: it changed state more than %d times within %s\nstate changes are not sent until it is stable for %s",
+			event.Endpoint,
+			threshold,
+			window,
+			stable,
+		)
+	}
+
+	state := "UP"
+	if event.Error != nil {
+		state = fmt.Sprintf("DOWN: %s", event.Error.Error())
+	}
+
+	return fmt.Sprintf(
+		"%s is stable for %s, it is %s\nflapped for %s, %d state changes were not sent",
+		event.Endpoint,
+		stable,
+		state,
+		now.Sub(event.FlappingSince).Round(time.Second),
+		event.Transitions,
+	)
+}
+
+// sendFlappingToClients
+// Summary of the flapping brings incidents in line with the final state:
+// endpoint that stayed down has an open incident, endpoint that is up has none
+func sendFlappingToClients(ctx context.Context, bot *tele.Bot, event RequestError) {
+	var incidentId int64
+	if event.Event == EventStable {
+		var err error
+		incidentId, err = settleIncident(ctx, botStorage.q, event)
+		if err != nil {
+			log.Error().Str("endpoint", event.Endpoint).Err(err).Msg("settle incident after flapping")
+		}
+		if incidentId != 0 {
+			if err := startEscalations(ctx, botStorage.q, incidentId, event.Endpoint, time.Now()); err != nil {
+				log.Error().Str("endpoint", event.Endpoint).Err(err).Msg("start escalations")
+			}
+		}
+	}
+
+	message := flappingMessage(event, time.Now())
+	for _, clientId := range alertRecipients(ctx, event.Endpoint, "") {
+		sent, err := bot.Send(&tele.User{ID: clientId}, message)
+		if err != nil {
+			log.Error().Int64("client", clientId).Err(err).Msg("send flapping notification")
+			continue
+		}
+
+		recordIncidentMessage(ctx, incidentId, clientId, sent)
+	}
+}
+
+// settleIncident
+// Returns the open incident of the endpoint that is down,
+// zero when the endpoint is up and its incident is resolved
+func settleIncident(ctx context.Context, q *monitor_db.Queries, event RequestError) (int64, error) {
+	if event.Error != nil {
+		return openIncident(ctx, q, event)
+	}
+
+	incident, err := q.GetOpenIncident(ctx, event.Endpoint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, q.ResolveIncident(ctx, monitor_db.ResolveIncidentParams{
+		Resolvedat: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:         incident.ID,
+	})
+}
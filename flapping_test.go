@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackFlapping(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &EndpointRequest{FlapTransitions: 3, FlapWindow: time.Hour, FlapStable: 30 * time.Minute}
+
+	var events []EndpointEvent
+	for minute := 0; minute < 10; minute++ {
+		if event := request.trackFlapping(true, start.Add(time.Duration(minute)*time.Minute)); event != "" {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 1 || events[0] != EventFlapping {
+		t.Fatalf("expected single flapping event, got %v", events)
+	}
+	if request.suppressedTransitions != 7 {
+		t.Errorf("expected 7 suppressed transitions, got %d", request.suppressedTransitions)
+	}
+
+	lastTransition := start.Add(9 * time.Minute)
+	if event := request.trackFlapping(false, lastTransition.Add(29*time.Minute)); event != "" {
+		t.Errorf("expected flapping to last until stable, got %s", event)
+	}
+	if event := request.trackFlapping(false, lastTransition.Add(30*time.Minute)); event != EventStable {
+		t.Errorf("expected endpoint to become stable, got %q", event)
+	}
+	if request.flapping || len(request.transitions) != 0 {
+		t.Errorf("expected flapping state to be reset, got %+v", request.checkState)
+	}
+}
+
+func TestTrackFlappingWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &EndpointRequest{FlapTransitions: 3, FlapWindow: time.Hour}
+
+	// transitions spread wider than the window are regular state changes
+	for i := 0; i < 10; i++ {
+		if event := request.trackFlapping(true, start.Add(time.Duration(i)*25*time.Minute)); event != "" {
+			t.Fatalf("unexpected %s event after %d transitions", event, i+1)
+		}
+	}
+}
+
+func TestFlappingSummary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: "https://api.com"},
+		Error:           errors.New("timeout"),
+		Event:           EventStable,
+		Transitions:     58,
+		FlappingSince:   start,
+	}
+
+	message := flappingMessage(event, start.Add(8*time.Hour))
+	for _, expected := range []string{"DOWN: timeout", "flapped for 8h0m0s", "58 state changes"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("expected %q in %q", expected, message)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	HostLimitConfig struct {
+		// MaxConcurrent checks of the host, 0 means unlimited
+		MaxConcurrent int `yaml:"maxConcurrent"`
+		// MinSpacing between starts of the checks of the host
+		MinSpacing time.Duration `yaml:"minSpacing"`
+	}
+
+	// HostLimiter
+	// Politeness limits that are applied before the check
+	// is handed to a worker: concurrency and spacing per host
+	// and the global rate of checks
+	HostLimiter struct {
+		lock        sync.Mutex
+		defaults    HostLimitConfig
+		overrides   map[string]HostLimitConfig
+		hosts       map[string]*hostState
+		rateSpacing time.Duration
+		nextStart   time.Time
+	}
+
+	hostState struct {
+		active    int
+		lastStart time.Time
+		released  chan struct{}
+	}
+
+	// limitedTransport
+	// Requests of a crawl check are limited like separate checks,
+	// the slot of the host is held until the response body is closed
+	limitedTransport struct {
+		limiter *HostLimiter
+		base    http.RoundTripper
+	}
+
+	releasingBody struct {
+		io.ReadCloser
+		release func()
+		once    sync.Once
+	}
+)
+
+func NewHostLimiter(config MonitorConfig) *HostLimiter {
+	limiter := new(HostLimiter)
+
+	limiter.defaults = HostLimitConfig{
+		MaxConcurrent: config.MaxChecksPerHost,
+		MinSpacing:    config.MinHostSpacing,
+	}
+	limiter.overrides = make(map[string]HostLimitConfig, len(config.HostLimits))
+	for host, limits := range config.HostLimits {
+		limiter.overrides[strings.ToLower(host)] = limits
+	}
+	limiter.hosts = make(map[string]*hostState)
+	if config.MaxChecksPerSecond > 0 {
+		limiter.rateSpacing = time.Duration(float64(time.Second) / config.MaxChecksPerSecond)
+	}
+
+	return limiter
+}
+
+// Acquire
+// Blocks until the check of the endpoint may start,
+// every successful Acquire must be followed by Release
+func (l *HostLimiter) Acquire(ctx context.Context, endpoint string) error {
+	host := endpointHost(endpoint)
+
+	for {
+		wait, released, reserved := l.reserve(host, time.Now())
+		if !reserved {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-released:
+			}
+			continue
+		}
+
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.Release(endpoint)
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// reserve
+// Takes a concurrency slot of the host and the earliest start time
+// allowed by host spacing and global rate,
+// when the host has no free slots returns channel that is closed on release
+func (l *HostLimiter) reserve(host string, now time.Time) (time.Duration, <-chan struct{}, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	state, exists := l.hosts[host]
+	if !exists {
+		state = &hostState{released: make(chan struct{})}
+		l.hosts[host] = state
+	}
+
+	limits := l.limitsOf(host)
+	if limits.MaxConcurrent > 0 && state.active >= limits.MaxConcurrent {
+		return 0, state.released, false
+	}
+
+	startAt := now
+	if l.rateSpacing > 0 {
+		if l.nextStart.After(startAt) {
+			startAt = l.nextStart
+		}
+		l.nextStart = startAt.Add(l.rateSpacing)
+	}
+	if hostStart := state.lastStart.Add(limits.MinSpacing); !state.lastStart.IsZero() && hostStart.After(startAt) {
+		startAt = hostStart
+	}
+
+	state.active++
+	state.lastStart = startAt
+
+	return startAt.Sub(now), nil, true
+}
+
+func (l *HostLimiter) Release(endpoint string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	host := endpointHost(endpoint)
+	state, exists := l.hosts[host]
+	if !exists {
+		return
+	}
+
+	state.active--
+	close(state.released)
+	state.released = make(chan struct{})
+
+	if state.active == 0 && time.Since(state.lastStart) > l.limitsOf(host).MinSpacing {
+		delete(l.hosts, host)
+	}
+}
+
+// Client
+// Http client whose requests wait for host limits,
+// nil limiter returns the default client
+func (l *HostLimiter) Client() *http.Client {
+	if l == nil {
+		return http.DefaultClient
+	}
+
+	return &http.Client{Transport: limitedTransport{limiter: l, base: http.DefaultTransport}}
+}
+
+func (t limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	endpoint := request.URL.String()
+	if err := t.limiter.Acquire(request.Context(), endpoint); err != nil {
+		return nil, err
+	}
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		t.limiter.Release(endpoint)
+		return nil, err
+	}
+	response.Body = &releasingBody{
+		ReadCloser: response.Body,
+		release:    func() { t.limiter.Release(endpoint) },
+	}
+
+	return response, nil
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
+
+func (l *HostLimiter) limitsOf(host string) HostLimitConfig {
+	if limits, exists := l.overrides[host]; exists {
+		return limits
+	}
+
+	return l.defaults
+}
+
+func endpointHost(endpoint string) string {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+
+	return strings.ToLower(endpointUrl.Hostname())
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterConcurrency(t *testing.T) {
+	limiter := NewHostLimiter(MonitorConfig{MaxChecksPerHost: 2})
+
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			endpoint := "https://api.com/items"
+			if err := limiter.Acquire(context.Background(), endpoint); err != nil {
+				t.Error(err)
+				return
+			}
+			current := active.Add(1)
+			for {
+				observed := maxActive.Load()
+				if current <= observed || maxActive.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			active.Add(-1)
+			limiter.Release(endpoint)
+		}()
+	}
+	wg.Wait()
+
+	if maxActive.Load() != 2 {
+		t.Errorf("expected at most 2 concurrent checks, got %d", maxActive.Load())
+	}
+}
+
+func TestHostLimiterSpacing(t *testing.T) {
+	limiter := NewHostLimiter(MonitorConfig{
+		MinHostSpacing: time.Second,
+		HostLimits: map[string]HostLimitConfig{
+			"API.com": {MinSpacing: time.Minute},
+		},
+	})
+	now := time.Now()
+
+	if wait, _, _ := limiter.reserve("other.com", now); wait != 0 {
+		t.Errorf("expected first check to start right away, waited %s", wait)
+	}
+	if wait, _, _ := limiter.reserve("other.com", now); wait != time.Second {
+		t.Errorf("expected default spacing, waited %s", wait)
+	}
+	limiter.reserve("api.com", now)
+	if wait, _, _ := limiter.reserve("api.com", now); wait != time.Minute {
+		t.Errorf("expected host override spacing, waited %s", wait)
+	}
+}
+
+func TestHostLimiterGlobalRate(t *testing.T) {
+	limiter := NewHostLimiter(MonitorConfig{MaxChecksPerSecond: 4})
+	now := time.Now()
+
+	var waits []time.Duration
+	for _, host := range []string{"a.com", "b.com", "c.com"} {
+		wait, _, _ := limiter.reserve(host, now)
+		waits = append(waits, wait)
+	}
+
+	expected := []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Errorf("expected checks to be spaced by global rate, got %v", waits)
+			break
+		}
+	}
+}
+
+func TestHostLimiterAcquireCancelled(t *testing.T) {
+	limiter := NewHostLimiter(MonitorConfig{MaxChecksPerHost: 1})
+	if err := limiter.Acquire(context.Background(), "tcp://db.local:5432"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx, "postgres://db.local/app"); err == nil {
+		t.Error("expected second check of the host to wait for the first one")
+	}
+}
+
+func TestCrawlPagesWithinHostLimits(t *testing.T) {
+	var active, maxActive atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			observed := maxActive.Load()
+			if current <= observed || maxActive.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	limiter := NewHostLimiter(MonitorConfig{MaxChecksPerHost: 1})
+	pageUrls := make([]string, 0, 2*crawlConcurrency)
+	for i := 0; i < cap(pageUrls); i++ {
+		pageUrls = append(pageUrls, fmt.Sprintf("%s/page/%d", server.URL, i))
+	}
+
+	for _, page := range fetchPages(limiter.Client(), pageUrls, true, time.Second) {
+		if page.err != nil || page.status != http.StatusOK {
+			t.Fatalf("expected page %s to be fetched, got %d %v", page.url, page.status, page.err)
+		}
+	}
+	if maxActive.Load() != 1 {
+		t.Errorf("expected pages to be fetched one at a time, got %d", maxActive.Load())
+	}
+	if len(limiter.hosts) != 0 {
+		t.Errorf("expected all host slots to be released, got %+v", limiter.hosts)
+	}
+}
+
+func TestThrottledHostKeepsSingleCheckPerEndpoint(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	monitor := NewHttpMonitor(MonitorConfig{AmountOfWorkers: 1, MinHostSpacing: 100 * time.Millisecond}, nil)
+	requests := []*EndpointRequest{
+		{Endpoint: server.URL + "/a", RequiredStatus: http.StatusOK, TimeoutInSeconds: 5},
+		{Endpoint: server.URL + "/b", RequiredStatus: http.StatusOK, TimeoutInSeconds: 5},
+	}
+	for _, request := range requests {
+		monitor.AddRequest(request)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.monitorWorker(ctx, 0, nil)
+
+	// checks are due far more often than the host spacing lets them run
+	skipped := 0
+	for i := 0; i < 100; i++ {
+		for _, request := range requests {
+			if !monitor.receive(ctx, DueRequest{Request: request, DueAt: time.Now()}) {
+				skipped++
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if skipped == 0 {
+		t.Error("expected due runs of pending checks to be skipped")
+	}
+
+	// nothing is queued behind the limiter, checks stop with the schedule
+	time.Sleep(250 * time.Millisecond)
+	ran := hits.Load()
+	time.Sleep(300 * time.Millisecond)
+	if hits.Load() != ran {
+		t.Errorf("expected no backlog of stale checks, got %d more", hits.Load()-ran)
+	}
+	if ran > 10 {
+		t.Errorf("expected checks to keep host spacing, got %d", ran)
+	}
+
+	monitor.lock.Lock()
+	pending := len(monitor.pending)
+	monitor.lock.Unlock()
+	if pending != 0 {
+		t.Errorf("expected finished checks to leave no pending endpoints, got %d", pending)
+	}
+}
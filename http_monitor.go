@@ -6,46 +6,154 @@ import (
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"pafaul/telegram-http-monitor/monitor_db"
+	"strings"
 	"sync"
 	"time"
 )
 
 type (
 	EndpointRequest struct {
-		Endpoint     string `json:"endpoint" yaml:"endpoint"`
-		lock         sync.Locker
-		requestError error
+		Endpoint         string        `json:"endpoint" yaml:"endpoint"`
+		TimeoutInSeconds int           `json:"timeoutInSeconds,omitempty" yaml:"timeoutInSeconds,omitempty"`
+		Interval         time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+		Cron             string        `json:"cron,omitempty" yaml:"cron,omitempty"`
+		Tags             string        `json:"tags,omitempty" yaml:"tags,omitempty"`
+		Retries          int           `json:"retries,omitempty" yaml:"retries,omitempty"`
+		RetryInterval    time.Duration `json:"retryInterval,omitempty" yaml:"retryInterval,omitempty"`
+		DownInterval     time.Duration `json:"downInterval,omitempty" yaml:"downInterval,omitempty"`
+		DownBackoff      float64       `json:"downBackoff,omitempty" yaml:"downBackoff,omitempty"`
+		MaxDownInterval  time.Duration `json:"maxDownInterval,omitempty" yaml:"maxDownInterval,omitempty"`
+		AlertAfter       int           `json:"alertAfter,omitempty" yaml:"alertAfter,omitempty"`
+		AlertAfterTime   time.Duration `json:"alertAfterTime,omitempty" yaml:"alertAfterTime,omitempty"`
+		RecoverAfter     int           `json:"recoverAfter,omitempty" yaml:"recoverAfter,omitempty"`
+		FlapTransitions  int           `json:"flapTransitions,omitempty" yaml:"flapTransitions,omitempty"`
+		FlapWindow       time.Duration `json:"flapWindow,omitempty" yaml:"flapWindow,omitempty"`
+		FlapStable       time.Duration `json:"flapStable,omitempty" yaml:"flapStable,omitempty"`
+		PingCount        int           `json:"pingCount,omitempty" yaml:"pingCount,omitempty"`
+		MaxPacketLoss    float64       `json:"maxPacketLoss,omitempty" yaml:"maxPacketLoss,omitempty"`
+		MaxRtt           time.Duration `json:"maxRtt,omitempty" yaml:"maxRtt,omitempty"`
+		Payload          string        `json:"payload,omitempty" yaml:"payload,omitempty"`
+		PayloadHex       string        `json:"payloadHex,omitempty" yaml:"payloadHex,omitempty"`
+		Expect           string        `json:"expect,omitempty" yaml:"expect,omitempty"`
+		Preset           string        `json:"preset,omitempty" yaml:"preset,omitempty"`
+		DnsName          string        `json:"dnsName,omitempty" yaml:"dnsName,omitempty"`
+		DnsType          string        `json:"dnsType,omitempty" yaml:"dnsType,omitempty"`
+		Query            string        `json:"query,omitempty" yaml:"query,omitempty"`
+		Info             string        `json:"info,omitempty" yaml:"info,omitempty"`
+		Crawl            string        `json:"crawl,omitempty" yaml:"crawl,omitempty"`
+		CrawlDepth       int           `json:"crawlDepth,omitempty" yaml:"crawlDepth,omitempty"`
+		CrawlBudget      int           `json:"crawlBudget,omitempty" yaml:"crawlBudget,omitempty"`
+		RequiredStatus   int           `json:"requiredStatus,omitempty" yaml:"requiredStatus,omitempty"`
+		Inverted         bool          `json:"inverted,omitempty" yaml:"inverted,omitempty"`
+		Method           string        `json:"method,omitempty" yaml:"method,omitempty"`
+		Body             string        `json:"body,omitempty" yaml:"body,omitempty"`
+		ContentType      string        `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+		XPath            string        `json:"xpath,omitempty" yaml:"xpath,omitempty"`
+		Css              string        `json:"css,omitempty" yaml:"css,omitempty"`
+		JsonSchema       string        `json:"jsonSchema,omitempty" yaml:"jsonSchema,omitempty"`
+		password         string
+		compiledSchema   *jsonschema.Schema
+		cronSchedule     *CronSchedule
+		lock             sync.Locker
+		checkState       `json:"-" yaml:"-"`
 	}
 
+	// checkState
+	// State of the endpoint between its checks,
+	// it is kept when settings are changed and is not stored
+	checkState struct {
+		requestError          error
+		failedChecks          int
+		passedChecks          int
+		failingSince          time.Time
+		transitions           []time.Time
+		flapping              bool
+		flappingSince         time.Time
+		suppressedTransitions int
+	}
+
+	// EndpointEvent
+	// Change of the endpoint state subscribers are notified about
+	EndpointEvent string
+
 	RequestError struct {
 		EndpointRequest
-		Error error `json:"error"`
+		Error       error         `json:"error"`
+		Timings     CheckTimings  `json:"timings"`
+		Diagnostics string        `json:"diagnostics"`
+		Category    ErrorCategory `json:"category"`
+		Event       EndpointEvent `json:"event"`
+		// DownSince and FailedChecks describe the down period of the endpoint
+		DownSince    time.Time `json:"downSince"`
+		FailedChecks int       `json:"failedChecks"`
+		// Transitions of the flapping endpoint, the ones after the first are not sent
+		Transitions   int       `json:"transitions"`
+		FlappingSince time.Time `json:"flappingSince"`
 	}
 
 	HttpMonitor struct {
 		lock            sync.Mutex
 		amountOfWorkers int
-		workerChannel   chan *EndpointRequest
-		requestIterator *RequestIterator
+		maxWorkers      int
+		autoscale       bool
+		lagAlert        time.Duration
+		lastLagReport   LagReport
+		pool            *WorkerPool
+		lagStats        *LagStats
+		lagAlerts       chan LagReport
+		workerChannel   chan DueRequest
+		resultChannel   chan CheckResult
+		errorChannel    chan<- RequestError
+		scheduler       *Scheduler
+		hostLimiter     *HostLimiter
+		secrets         *SecretBox
+		// pending endpoints are waiting for host limits, a worker or are checked
+		pending map[string]bool
+	}
+
+	WorkerPoolStatus struct {
+		Workers    int
+		MinWorkers int
+		MaxWorkers int
+		Autoscale  bool
+		LastReport LagReport
 	}
 
 	IHttpMonitor interface {
 		StartMonitor(ctx context.Context, db *sql.DB, errorChannel chan<- RequestError)
 		AddRequest(request *EndpointRequest)
+		UpdateRequest(request *EndpointRequest) bool
+		GetRequest(endpoint string) (EndpointRequest, bool)
 		RemoveRequest(request *EndpointRequest) bool
 		RequestExists(request *EndpointRequest) bool
 	}
 )
 
-func NewHttpMonitor(amountOfWorkers int) *HttpMonitor {
+func NewHttpMonitor(config MonitorConfig, secrets *SecretBox) *HttpMonitor {
 	monitor := new(HttpMonitor)
 
+	amountOfWorkers := config.AmountOfWorkers
 	monitor.amountOfWorkers = amountOfWorkers
-	monitor.workerChannel = make(chan *EndpointRequest, amountOfWorkers)
+	monitor.maxWorkers = max(config.MaxWorkers, amountOfWorkers)
+	monitor.autoscale = monitor.maxWorkers > amountOfWorkers
+	monitor.lagAlert = config.LagAlert
+	monitor.secrets = secrets
+	monitor.workerChannel = make(chan DueRequest, amountOfWorkers)
+	monitor.resultChannel = make(chan CheckResult, resultsBufferSize)
+	monitor.lagAlerts = make(chan LagReport, 1)
+	monitor.lagStats = NewLagStats(time.Now())
+	monitor.pool = NewWorkerPool(amountOfWorkers, monitor.monitorWorker)
 
-	monitor.requestIterator = NewRequestIterator(amountOfWorkers)
+	monitor.scheduler = NewScheduler(amountOfWorkers, config.Jitter)
+	monitor.hostLimiter = NewHostLimiter(config)
+	monitor.pending = make(map[string]bool)
 
 	return monitor
 }
@@ -53,108 +161,531 @@ func NewHttpMonitor(amountOfWorkers int) *HttpMonitor {
 func (m *HttpMonitor) StartMonitor(ctx context.Context, db *sql.DB, errorChannel chan<- RequestError) {
 	q := monitor_db.New(db)
 	requests, _ := q.GetEndpointsToMonitor(context.Background())
+	incidents, err := q.GetOpenIncidents(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("load open incidents")
+	}
+
+	openIncidents := make(map[string]monitor_db.GetOpenIncidentsRow, len(incidents))
+	for _, incident := range incidents {
+		openIncidents[incident.Url] = incident
+	}
+
 	for _, r := range requests {
-		m.requestIterator.Add(&EndpointRequest{
-			Endpoint:     r.Url,
-			lock:         &sync.Mutex{},
-			requestError: nil,
-		})
+		request := m.RestoreRequest(r.Url, r.Settings, r.Secret)
+		// endpoint that was down before restart is told to be up when it recovers
+		if incident, open := openIncidents[r.Url]; open {
+			request.restoreDown(errors.New(incident.Error), incident.Startedat, int(incident.Failedchecks))
+		}
+		m.scheduler.Add(request, false)
 	}
 
-	go m.requestIterator.Start(ctx)
+	go m.scheduler.Start(ctx)
+	go recordResults(ctx, q, m.resultChannel)
 
-	log.Info().Int("amount", m.amountOfWorkers).Msg("starting monitor workers")
+	log.Info().Int("amount", m.pool.Size()).Msg("starting monitor workers")
 
-	var wg sync.WaitGroup
-	for id := 0; id < m.amountOfWorkers; id++ {
-		go func(id int) {
-			wg.Add(1)
-			monitorWorker(ctx, id, m.workerChannel, errorChannel)
-			wg.Done()
-		}(id)
-	}
+	m.errorChannel = errorChannel
+	m.pool.Start(ctx)
+	go m.superviseWorkers(ctx)
 
 	for {
-		r, ok := <-m.requestIterator.ReceiveChannel
-		if !ok {
-			log.Warn().Msg("request iterator has closed the channel")
+		select {
+		case <-ctx.Done():
+			m.pool.Wait()
 			return
+		case r := <-m.scheduler.ReceiveChannel:
+			m.receive(ctx, r)
 		}
+	}
+}
+
+// superviseWorkers
+// Periodically reports lag of the checks, resizes the pool
+// when autoscaling is enabled and raises lag alerts for admins
+func (m *HttpMonitor) superviseWorkers(ctx context.Context) {
+	ticker := time.NewTicker(poolEvaluateInterval)
+	defer ticker.Stop()
 
+	var lastAlert time.Time
+	for {
 		select {
 		case <-ctx.Done():
-			wg.Wait()
-			close(m.workerChannel)
 			return
-		default:
+		case now := <-ticker.C:
+			report := m.lagStats.Take(now, m.pool.Size())
+
+			m.lock.Lock()
+			m.lastLagReport = report
+			autoscale := m.autoscale
+			m.lock.Unlock()
+
+			if autoscale {
+				if size := autoscaleSize(report, m.amountOfWorkers, m.maxWorkers); size != report.Workers {
+					log.Info().Int("from", report.Workers).Int("to", size).Str("report", report.String()).Msg("autoscale workers")
+					m.pool.Resize(size)
+				}
+			}
+
+			if report.MaxLag < m.lagAlert || now.Sub(lastAlert) < lagAlertCooldown {
+				continue
+			}
+			select {
+			case m.lagAlerts <- report:
+				lastAlert = now
+			default:
+			}
 		}
+	}
+}
+
+func (m *HttpMonitor) LagAlerts() <-chan LagReport {
+	return m.lagAlerts
+}
 
-		m.workerChannel <- r
+func (m *HttpMonitor) WorkerPoolStatus() WorkerPoolStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return WorkerPoolStatus{
+		Workers:    m.pool.Size(),
+		MinWorkers: m.amountOfWorkers,
+		MaxWorkers: m.maxWorkers,
+		Autoscale:  m.autoscale,
+		LastReport: m.lastLagReport,
 	}
 }
 
+// ResizeWorkers
+// Manual size is kept until autoscaling is enabled again
+func (m *HttpMonitor) ResizeWorkers(size int) {
+	m.lock.Lock()
+	m.autoscale = false
+	m.lock.Unlock()
+
+	m.pool.Resize(size)
+}
+
+// EnableAutoscale
+// Autoscaling needs maxWorkers above amountOfWorkers in the config
+func (m *HttpMonitor) EnableAutoscale() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.autoscale = m.maxWorkers > m.amountOfWorkers
+	return m.autoscale
+}
+
+// RestoreRequest
+// Builds the request from stored settings and sealed credentials,
+// broken settings are logged so the endpoint is still checked
+func (m *HttpMonitor) RestoreRequest(endpoint, settings string, secret []byte) *EndpointRequest {
+	request := &EndpointRequest{
+		Endpoint: endpoint,
+		lock:     &sync.Mutex{},
+	}
+	if err := decodeEndpointSettings(settings, request); err != nil {
+		log.Error().Str("endpoint", endpoint).Err(err).Msg("decode endpoint settings")
+	}
+	request.Endpoint = endpoint
+	if err := validateEndpointSettings(request); err != nil {
+		log.Error().Str("endpoint", endpoint).Err(err).Msg("validate endpoint settings")
+	}
+	if len(secret) > 0 {
+		password, err := m.secrets.Open(secret)
+		if err != nil {
+			log.Error().Str("endpoint", endpoint).Err(err).Msg("open endpoint secret")
+		}
+		request.password = password
+	}
+
+	return request
+}
+
+// receive
+// Due request whose previous check is still pending is skipped,
+// so a host that is slower than its schedule gets a single check at a time
+// instead of a growing queue of stale ones. Its next run stays scheduled
+func (m *HttpMonitor) receive(ctx context.Context, r DueRequest) bool {
+	m.lock.Lock()
+	pending := m.pending[r.Request.Endpoint]
+	m.pending[r.Request.Endpoint] = true
+	m.lock.Unlock()
+
+	if pending {
+		log.Debug().Str("endpoint", r.Request.Endpoint).Msg("previous check is pending, due run is skipped")
+		return false
+	}
+
+	go m.dispatch(ctx, r)
+	return true
+}
+
+func (m *HttpMonitor) checkDone(endpoint string) {
+	m.lock.Lock()
+	delete(m.pending, endpoint)
+	m.lock.Unlock()
+}
+
+// dispatch
+// Waits for host limits in its own goroutine,
+// so checks of a busy host do not hold back other hosts.
+// Waiting for host limits is intended, it is not counted as lag
+func (m *HttpMonitor) dispatch(ctx context.Context, r DueRequest) {
+	if r.Request.limitedPerPage() {
+		select {
+		case <-ctx.Done():
+			m.checkDone(r.Request.Endpoint)
+		case m.workerChannel <- r:
+		}
+		return
+	}
+
+	waitStart := time.Now()
+	if err := m.hostLimiter.Acquire(ctx, r.Request.Endpoint); err != nil {
+		m.checkDone(r.Request.Endpoint)
+		return
+	}
+	r.DueAt = r.DueAt.Add(time.Since(waitStart))
+
+	select {
+	case <-ctx.Done():
+		m.hostLimiter.Release(r.Request.Endpoint)
+		m.checkDone(r.Request.Endpoint)
+	case m.workerChannel <- r:
+	}
+}
+
+// limitedPerPage
+// Crawl checks many pages, every page waits for host limits on its own
+// instead of the whole check holding a single slot of the host
+func (r *EndpointRequest) limitedPerPage() bool {
+	return len(r.Crawl) > 0
+}
+
+// CheckEndpoint
+// Checks the request outside of the monitor schedule
+// within the same host limits as scheduled checks
+func (m *HttpMonitor) CheckEndpoint(ctx context.Context, r *EndpointRequest) (CheckReport, error) {
+	if !r.limitedPerPage() {
+		if err := m.hostLimiter.Acquire(ctx, r.Endpoint); err != nil {
+			return CheckReport{}, err
+		}
+		defer m.hostLimiter.Release(r.Endpoint)
+	}
+
+	return checkEndpoint(r, m.hostLimiter)
+}
+
+// CheckEndpoints
+// Checks endpoints concurrently outside of the monitor schedule
+// within host limits, errors are returned in the same order as endpoints
+func (m *HttpMonitor) CheckEndpoints(ctx context.Context, endpoints []EndpointRequest) []error {
+	errs := make([]error, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = m.CheckEndpoint(ctx, &endpoints[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
 func (m *HttpMonitor) AddRequest(request *EndpointRequest) {
 	request.lock = &sync.Mutex{}
-	m.requestIterator.Add(request)
+	m.scheduler.Add(request, true)
+}
+
+func (m *HttpMonitor) PreviewSchedule(endpoints []string, limit int) []ScheduledCheck {
+	return m.scheduler.Preview(endpoints, limit)
+}
+
+func (m *HttpMonitor) UpdateRequest(request *EndpointRequest) bool {
+	return m.scheduler.Update(request)
+}
+
+func (m *HttpMonitor) GetRequest(endpoint string) (EndpointRequest, bool) {
+	return m.scheduler.Get(endpoint)
+}
+
+// EndpointError
+// Returns the error of the endpoint that is currently down
+func (m *HttpMonitor) EndpointError(endpoint string) error {
+	return m.scheduler.RequestError(endpoint)
 }
 
 func (m *HttpMonitor) RemoveRequest(request *EndpointRequest) bool {
-	return m.requestIterator.Remove(request)
+	return m.scheduler.Remove(request)
 }
 
 func (m *HttpMonitor) RequestExists(request *EndpointRequest) bool {
-	return m.requestIterator.RequestExists(request)
+	return m.scheduler.RequestExists(request)
 }
 
-func monitorWorker(ctx context.Context, workerId int, workerChannel <-chan *EndpointRequest, updateChannel chan<- RequestError) {
+func (m *HttpMonitor) monitorWorker(ctx context.Context, workerId int, stop <-chan struct{}) {
 	log.Info().Int("workerId", workerId).Msg("worker is starting")
+	updateChannel := m.errorChannel
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info().Int("workerId", workerId).Msg("stopping worker")
 			return
-		case r := <-workerChannel:
+		case <-stop:
+			log.Info().Int("workerId", workerId).Msg("worker is removed from pool")
+			return
+		case due := <-m.workerChannel:
+			r := due.Request
 			log.Info().Int("workerId", workerId).Str("endpoint", r.Endpoint).Msg("requesting")
 			r.lock.Lock()
-			err := checkLiveliness(http.DefaultClient, r.Endpoint)
-			if err != nil && r.requestError == nil {
-				r.requestError = err
-				updateChannel <- RequestError{
+			checkedAt := time.Now()
+			report, err := checkEndpoint(r, m.hostLimiter)
+			if !r.limitedPerPage() {
+				m.hostLimiter.Release(r.Endpoint)
+			}
+			m.checkDone(r.Endpoint)
+			m.lagStats.Record(checkedAt.Sub(due.DueAt), time.Since(checkedAt))
+			category := classifyError(err)
+			result := CheckResult{
+				Endpoint:  r.Endpoint,
+				CheckedAt: checkedAt,
+				Timings:   report.Timings,
+				Error:     err,
+				Category:  category,
+			}
+			select {
+			case m.resultChannel <- result:
+			default:
+				log.Warn().Str("endpoint", r.Endpoint).Msg("check results buffer is full, result is dropped")
+			}
+
+			downSince, failedChecks := r.failingSince, r.failedChecks
+			wentDown, recovered := r.trackCheck(err, checkedAt)
+			flapEvent := r.trackFlapping(wentDown || recovered, checkedAt)
+
+			var event *RequestError
+			switch {
+			case flapEvent != "":
+				event = &RequestError{
+					EndpointRequest: *r,
+					Error:           r.requestError,
+					Category:        category,
+					Event:           flapEvent,
+					DownSince:       r.failingSince,
+					Transitions:     r.suppressedTransitions,
+					FlappingSince:   r.flappingSince,
+				}
+			case r.flapping:
+				log.Debug().Str("endpoint", r.Endpoint).Msg("state change of flapping endpoint is suppressed")
+			case wentDown:
+				event = &RequestError{
 					EndpointRequest: *r,
 					Error:           err,
+					Timings:         report.Timings,
+					Diagnostics:     formatDiagnostics(r, err, checkedAt, &report),
+					Category:        category,
+					Event:           EventDown,
+					DownSince:       r.failingSince,
+					FailedChecks:    r.failedChecks,
+				}
+			case recovered:
+				event = &RequestError{
+					EndpointRequest: *r,
+					Timings:         report.Timings,
+					Event:           EventRecovered,
+					DownSince:       downSince,
+					FailedChecks:    failedChecks,
 				}
 			}
 
-			if r.requestError != nil && err == nil {
-				r.requestError = nil
-			}
+			delay := followUpDelay(r)
 			r.lock.Unlock()
+
+			// scheduler and receivers of the event lock the request themselves
+			if delay > 0 {
+				m.scheduler.Reschedule(r.Endpoint, delay)
+			}
+			if event != nil {
+				updateChannel <- *event
+			}
+		}
+	}
+}
+
+const (
+	EventDown      EndpointEvent = "down"
+	EventRecovered EndpointEvent = "recovered"
+	EventFlapping  EndpointEvent = "flapping"
+	EventStable    EndpointEvent = "stable"
+)
+
+var (
+	ErrUnsupportedScheme     = errors.New("unsupported endpoint scheme")
+	ErrUnexpectedlyReachable = errors.New("unexpectedly reachable")
+)
+
+const (
+	defaultTimeoutInSeconds = 5
+	maxResponseBodySize     = 5 << 20
+)
+
+// TagList
+// Tags are stored as comma separated list, e.g. tags=prod,api
+func (r *EndpointRequest) TagList() []string {
+	var tags []string
+	for _, tag := range strings.Split(r.Tags, ",") {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 {
+			tags = append(tags, tag)
 		}
 	}
+
+	return tags
 }
 
-func checkLiveliness(client *http.Client, endpoint string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(5))
+func validateEndpoint(endpoint string) error {
+	endpointUrl, err := url.ParseRequestURI(endpoint)
+	if err != nil {
+		return err
+	}
+
+	switch endpointUrl.Scheme {
+	case "http", "https", "ping":
+	case "udp", "tcp":
+		if len(endpointUrl.Port()) == 0 {
+			return fmt.Errorf("%s endpoint %s has no port", endpointUrl.Scheme, endpoint)
+		}
+	case "redis", "rediss", "postgres", "postgresql", "mysql":
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedScheme, endpointUrl.Scheme)
+	}
+
+	if len(endpointUrl.Hostname()) == 0 {
+		return fmt.Errorf("endpoint %s has no host", endpoint)
+	}
+
+	return nil
+}
+
+// checkEndpoint
+// Inverted endpoints must stay unreachable,
+// so any successful check is reported as ErrUnexpectedlyReachable
+// and any failed check counts as success.
+// Limiter is applied to the pages of crawl checks
+func checkEndpoint(r *EndpointRequest, limiter *HostLimiter) (CheckReport, error) {
+	var report CheckReport
+
+	start := time.Now()
+	err := runEndpointCheck(r, &report, limiter)
+	report.Timings.Total = time.Since(start)
+
+	if !r.Inverted {
+		return report, err
+	}
+
+	if err == nil {
+		return report, ErrUnexpectedlyReachable
+	}
+
+	log.Debug().Str("endpoint", r.Endpoint).Err(err).Msg("inverted endpoint is unreachable")
+	return report, nil
+}
+
+func runEndpointCheck(r *EndpointRequest, report *CheckReport, limiter *HostLimiter) error {
+	endpointUrl, err := url.Parse(r.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Second * time.Duration(r.TimeoutInSeconds)
+	if r.TimeoutInSeconds <= 0 {
+		timeout = time.Second * time.Duration(defaultTimeoutInSeconds)
+	}
+
+	switch endpointUrl.Scheme {
+	case "http", "https":
+		if len(r.Crawl) > 0 {
+			return checkCrawl(limiter.Client(), r, timeout)
+		}
+		return checkLiveliness(http.DefaultClient, r, timeout, report)
+	case "tcp":
+		return checkTcp(endpointUrl.Host, timeout)
+	case "ping":
+		return checkPing(endpointUrl.Hostname(), r, timeout)
+	case "udp":
+		return checkUdp(endpointUrl.Host, r, timeout)
+	case "redis", "rediss":
+		return checkRedis(endpointUrl, r, timeout)
+	case "postgres", "postgresql", "mysql":
+		return checkSqlDatabase(endpointUrl, r, timeout)
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedScheme, endpointUrl.Scheme)
+}
+
+// checkLiveliness
+// Phases of the request and the response are traced into the report.
+// Connection is not reused, so every check measures dns, connect and tls
+func checkLiveliness(client *http.Client, r *EndpointRequest, timeout time.Duration, report *CheckReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+
+	trace := newCheckTrace(report)
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+
+	method := http.MethodGet
+	if len(r.Method) > 0 {
+		method = strings.ToUpper(r.Method)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, r.Endpoint, strings.NewReader(r.Body))
 	if err != nil {
 		return err
 	}
+	if len(r.ContentType) > 0 {
+		request.Header.Set("Content-Type", r.ContentType)
+	}
+	request.Close = true
 
 	res, err := client.Do(request)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
-		return errors.New(fmt.Sprintf(
-			"Invalid status code. Received: %d, expected: 200 or 201",
-			res.StatusCode,
-		))
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBodySize))
+	trace.bodyTransferred()
+	report.Diagnostics.setResponse(res, body)
+	if err != nil {
+		return err
+	}
+
+	if r.RequiredStatus != 0 && res.StatusCode != r.RequiredStatus {
+		return &StatusCodeError{Received: res.StatusCode, Expected: []int{r.RequiredStatus}}
+	}
+
+	if r.RequiredStatus == 0 && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		return &StatusCodeError{Received: res.StatusCode, Expected: []int{http.StatusOK, http.StatusCreated}}
+	}
+
+	if err := checkResponseAssertions(r, body); err != nil {
+		return &AssertionError{Err: err}
 	}
 
 	return nil
 }
 
+func checkTcp(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
 var _ IHttpMonitor = (*HttpMonitor)(nil)
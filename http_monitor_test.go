@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestErrorConversion(t *testing.T) {
@@ -31,7 +34,8 @@ func TestCheckLiveliness(t *testing.T) {
 		new(url.Error),
 	}
 
-	receivedErrors := CheckEndpoints(endpointsToCheck)
+	monitor := NewHttpMonitor(MonitorConfig{AmountOfWorkers: 1}, nil)
+	receivedErrors := monitor.CheckEndpoints(context.Background(), endpointsToCheck)
 
 	for i, err := range receivedErrors {
 		t.Log(fmt.Sprintf("Checking errors with index: %d", i))
@@ -43,3 +47,51 @@ func TestCheckLiveliness(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkerFollowUpWhileRequestIsRead(t *testing.T) {
+	checking, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(checking)
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	monitor := NewHttpMonitor(MonitorConfig{AmountOfWorkers: 1}, nil)
+	request := &EndpointRequest{Endpoint: server.URL, RequiredStatus: http.StatusOK, TimeoutInSeconds: 5, Retries: 3}
+	monitor.AddRequest(request)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.monitorWorker(ctx, 0, nil)
+	monitor.workerChannel <- DueRequest{Request: request, DueAt: time.Now()}
+
+	// request is read while the worker holds it, failed check reschedules the retry
+	<-checking
+	read := make(chan struct{})
+	go func() {
+		monitor.GetRequest(request.Endpoint)
+		close(read)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	select {
+	case <-read:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker and request reader are deadlocked")
+	}
+
+	// retry is scheduled right after the worker releases the request
+	deadline := time.Now().Add(time.Second)
+	for {
+		checks := monitor.PreviewSchedule([]string{request.Endpoint}, 1)
+		if len(checks) == 1 && time.Until(checks[0].RunAt) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected retry to be scheduled, got %+v", checks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
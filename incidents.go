@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"time"
+)
+
+// openIncident
+// Incident lasts from the down alert until the endpoint recovers,
+// alert repeated after restart joins the incident that is still open
+func openIncident(ctx context.Context, q *monitor_db.Queries, requestErr RequestError) (int64, error) {
+	incident, err := q.GetOpenIncident(ctx, requestErr.Endpoint)
+	if err == nil {
+		return incident.ID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	startedAt := requestErr.DownSince
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
+	return q.OpenIncident(ctx, monitor_db.OpenIncidentParams{
+		Startedat: startedAt,
+		Error:     requestErr.Error.Error(),
+		Url:       requestErr.Endpoint,
+	})
+}
+
+// sendRecoveryToClients
+// Recovery is sent only to the chats that got the down alert,
+// as a reply to it so both messages are threaded
+func sendRecoveryToClients(ctx context.Context, bot *tele.Bot, recovery RequestError) {
+	incident, err := botStorage.q.GetOpenIncident(ctx, recovery.Endpoint)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Info().Str("endpoint", recovery.Endpoint).Msg("recovered endpoint has no open incident")
+		return
+	}
+	if err != nil {
+		log.Error().Str("endpoint", recovery.Endpoint).Err(err).Msg("load open incident")
+		return
+	}
+
+	recoveredAt := time.Now()
+	err = botStorage.q.ResolveIncident(ctx, monitor_db.ResolveIncidentParams{
+		Resolvedat: sql.NullTime{Time: recoveredAt, Valid: true},
+		ID:         incident.ID,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incident.ID).Err(err).Msg("resolve incident")
+	}
+
+	messages, err := botStorage.q.GetIncidentMessages(ctx, incident.ID)
+	if err != nil {
+		log.Error().Int64("incident", incident.ID).Err(err).Msg("load incident messages")
+		return
+	}
+
+	downSince := recovery.DownSince
+	if downSince.IsZero() {
+		downSince = incident.Startedat
+	}
+	message := recoveryMessage(recovery.Endpoint, downSince, recoveredAt, recovery.FailedChecks)
+
+	for _, alert := range messages {
+		chat := &tele.Chat{ID: alert.Chatid}
+		_, err := bot.Send(chat, message, &tele.SendOptions{
+			ReplyTo:           &tele.Message{ID: int(alert.Messageid), Chat: chat},
+			AllowWithoutReply: true,
+		})
+		if err != nil {
+			log.Error().Int64("chat", alert.Chatid).Err(err).Msg("send recovery")
+		}
+	}
+}
+
+func recoveryMessage(endpoint string, downSince, recoveredAt time.Time, failedChecks int) string {
+	return fmt.Sprintf(
+		"✅ %s is UP again\nwent down: %s\ndowntime: %s\nfailed checks: %d",
+		endpoint,
+		downSince.UTC().Format(time.DateTime),
+		recoveredAt.Sub(downSince).Round(time.Second),
+		failedChecks,
+	)
+}
+
+func pruneIncidents(ctx context.Context, q *monitor_db.Queries, before time.Time) error {
+	resolvedBefore := sql.NullTime{Time: before, Valid: true}
+	if err := q.RemoveIncidentMessagesResolvedBefore(ctx, resolvedBefore); err != nil {
+		return err
+	}
+
+	return q.RemoveIncidentsResolvedBefore(ctx, resolvedBefore)
+}
+
+// recordIncidentMessage
+// Later messages of the incident are sent as replies to the recorded one.
+// Content is kept to edit the message when the incident is acknowledged
+func recordIncidentMessage(ctx context.Context, incidentId, chatId int64, sent *tele.Message) {
+	if incidentId == 0 {
+		return
+	}
+
+	content, isDocument := messageContent(sent)
+	err := botStorage.q.AddIncidentMessage(ctx, monitor_db.AddIncidentMessageParams{
+		Incidentid: incidentId,
+		Chatid:     chatId,
+		Messageid:  int64(sent.ID),
+		Notifiedat: sql.NullTime{Time: time.Now(), Valid: true},
+		Content:    content,
+		Document:   isDocument,
+	})
+	if err != nil {
+		log.Error().Int64("incident", incidentId).Err(err).Msg("record alert message")
+	}
+}
+
+// messageContent
+// Alert with diagnostics is a document, its text is the caption
+func messageContent(message *tele.Message) (string, bool) {
+	if message.Document != nil {
+		return message.Caption, true
+	}
+
+	return message.Text, false
+}
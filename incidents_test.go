@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenIncidentIsReused(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+
+	downSince := time.Now().Add(-time.Hour)
+	down := RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("connection refused"),
+		DownSince:       downSince,
+	}
+	first, err := openIncident(ctx, botStorage.q, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := openIncident(ctx, botStorage.q, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatalf("expected open incident %d to be reused, got %d", first, second)
+	}
+
+	for _, checkErr := range []string{"connection refused", "", "timeout"} {
+		err := botStorage.q.AddCheckResult(ctx, monitor_db.AddCheckResultParams{
+			Url:       endpoint,
+			Checkedat: time.Now(),
+			Error:     checkErr,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	incidents, err := botStorage.q.GetOpenIncidents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].Failedchecks != 2 || incidents[0].Error != "connection refused" {
+		t.Fatalf("unexpected open incidents %+v", incidents)
+	}
+
+	err = botStorage.q.ResolveIncident(ctx, monitor_db.ResolveIncidentParams{
+		Resolvedat: sql.NullTime{Time: time.Now(), Valid: true},
+		ID:         first,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := botStorage.q.GetOpenIncident(ctx, endpoint); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected incident to be resolved, got %v", err)
+	}
+}
+
+func TestRecoveryAfterRestart(t *testing.T) {
+	request := &EndpointRequest{Retries: 2}
+	downSince := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	request.restoreDown(errors.New("timeout"), downSince, 0)
+
+	if wentDown, _ := request.trackCheck(errors.New("timeout"), downSince.Add(time.Minute)); wentDown {
+		t.Error("restored endpoint should not go down again")
+	}
+	if _, recovered := request.trackCheck(nil, downSince.Add(2*time.Minute)); !recovered {
+		t.Error("expected restored endpoint to recover")
+	}
+}
+
+func TestRecoveryMessage(t *testing.T) {
+	downSince := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	message := recoveryMessage("https://api.com", downSince, downSince.Add(90*time.Minute), 7)
+
+	for _, expected := range []string{"https://api.com is UP again", "2024-01-01 12:00:00", "downtime: 1h30m0s", "failed checks: 7"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("expected %q in recovery message %q", expected, message)
+		}
+	}
+}
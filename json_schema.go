@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+const (
+	jsonSchemaResource     = "schema.json"
+	maxReportedViolations  = 5
+	maxJsonSchemaFileBytes = 1 << 20
+)
+
+// compileJsonSchema
+// Schema is accepted both as json and as yaml,
+// so it can be written inline in yaml settings
+func compileJsonSchema(schema string) (*jsonschema.Schema, error) {
+	var document any
+	if err := yaml.Unmarshal([]byte(schema), &document); err != nil {
+		return nil, fmt.Errorf("parse json schema: %w", err)
+	}
+
+	schemaJson, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("parse json schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(jsonSchemaResource, bytes.NewReader(schemaJson)); err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile(jsonSchemaResource)
+}
+
+// checkJsonSchema
+// Schema is compiled once and kept with the request,
+// the worker holds request lock while checking it
+func checkJsonSchema(r *EndpointRequest, body []byte) error {
+	if r.compiledSchema == nil {
+		schema, err := compileJsonSchema(r.JsonSchema)
+		if err != nil {
+			return err
+		}
+		r.compiledSchema = schema
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("response is not valid json: %w", err)
+	}
+
+	err := r.compiledSchema.Validate(document)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	violations := schemaViolations(validationErr)
+	report := fmt.Sprintf("response violates json schema in %d places:\n", len(violations))
+	if len(violations) > maxReportedViolations {
+		report += strings.Join(violations[:maxReportedViolations], "\n")
+		report += fmt.Sprintf("\n... and %d more", len(violations)-maxReportedViolations)
+	} else {
+		report += strings.Join(violations, "\n")
+	}
+
+	return errors.New(report)
+}
+
+func schemaViolations(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		instanceLocation := err.InstanceLocation
+		if len(instanceLocation) == 0 {
+			instanceLocation = "/"
+		}
+		return []string{fmt.Sprintf("%s: %s", instanceLocation, err.Message)}
+	}
+
+	var violations []string
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+
+	return violations
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckJsonSchema(t *testing.T) {
+	schema := `
+type: object
+required: [id, items]
+properties:
+  id: {type: integer}
+  items:
+    type: array
+    items: {type: object, required: [name]}
+`
+	request := &EndpointRequest{JsonSchema: schema}
+
+	if err := checkJsonSchema(request, []byte(`{"id": 1, "items": [{"name": "a"}]}`)); err != nil {
+		t.Fatalf("expected valid response, got %s", err)
+	}
+
+	err := checkJsonSchema(request, []byte(`{"id": "1", "items": [{"name": "a"}, {}]}`))
+	if err == nil {
+		t.Fatal("expected schema violations")
+	}
+	for _, pointer := range []string{"/id:", "/items/1:"} {
+		if !strings.Contains(err.Error(), pointer) {
+			t.Errorf("expected violation at %s, got %s", pointer, err)
+		}
+	}
+
+	if err := checkJsonSchema(request, []byte(`not json`)); err == nil {
+		t.Error("expected invalid json to fail")
+	}
+}
+
+func TestCompileJsonSchemaFromJson(t *testing.T) {
+	if _, err := compileJsonSchema(`{"type": "object", "required": ["id"]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := compileJsonSchema(`{"type": "unknown"}`); err == nil {
+		t.Error("expected invalid schema to fail")
+	}
+}
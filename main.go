@@ -10,6 +10,8 @@ import (
 	"os/signal"
 	"runtime"
 	"sync"
+	// cron schedules may use any timezone, the image has no tzdata
+	_ "time/tzdata"
 )
 
 func main() {
@@ -33,14 +35,25 @@ func main() {
 		}
 	}()
 
-	httpMonitor := NewHttpMonitor(config.Monitor.AmountOfWorkers)
+	secrets, err := NewSecretBox(config.SecretKey)
+	if err != nil {
+		log.Error().Err(err).Msg("secret key error")
+		runtime.Goexit()
+	}
 
-	bot, botErr := NewBot(config, httpMonitor, db)
+	httpMonitor := NewHttpMonitor(config.Monitor, secrets)
+
+	bot, botErr := NewBot(config, httpMonitor, db, secrets)
 	if botErr != nil {
 		log.Error().Err(botErr).Msg("could not start bot")
 		runtime.Goexit()
 	}
 
+	if err := subscribeConfiguredChecks(context.Background(), config.Checks); err != nil {
+		log.Error().Err(err).Msg("subscribe checks from config")
+		runtime.Goexit()
+	}
+
 	errorChannel := make(chan RequestError)
 	cancel, wg := start(bot, httpMonitor, db, errorChannel)
 
@@ -64,7 +77,7 @@ func start(bot *tele.Bot, httpMonitor *HttpMonitor, db *sql.DB, errorChannel cha
 	senderCtx, cancel := context.WithCancel(context.Background())
 
 	var wg sync.WaitGroup
-	wg.Add(3)
+	wg.Add(7)
 
 	go func(wg *sync.WaitGroup) {
 		bot.Start()
@@ -81,5 +94,25 @@ func start(bot *tele.Bot, httpMonitor *HttpMonitor, db *sql.DB, errorChannel cha
 		wg.Done()
 	}(&wg)
 
+	go func(wg *sync.WaitGroup) {
+		SendMaintenanceSummaries(senderCtx, bot)
+		wg.Done()
+	}(&wg)
+
+	go func(wg *sync.WaitGroup) {
+		SendReminders(senderCtx, bot)
+		wg.Done()
+	}(&wg)
+
+	go func(wg *sync.WaitGroup) {
+		RunEscalations(senderCtx, bot)
+		wg.Done()
+	}(&wg)
+
+	go func(wg *sync.WaitGroup) {
+		SendLagAlertsToAdmins(senderCtx, bot, httpMonitor.LagAlerts())
+		wg.Done()
+	}(&wg)
+
 	return cancel, &wg
 }
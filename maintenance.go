@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// MaintenanceWindow
+	// Alerts of the matching endpoints are not sent to the client
+	// while the window is active, checks still run and are recorded.
+	// Target is an endpoint, tag:name or all
+	MaintenanceWindow struct {
+		Id         int64
+		ClientId   int64
+		Target     string
+		StartsAt   time.Time
+		Duration   time.Duration
+		Recurrence string
+	}
+)
+
+const (
+	maintenanceTargetAll    = "all"
+	maintenanceTagPrefix    = "tag:"
+	recurrenceDaily         = "daily"
+	recurrenceWeekly        = "weekly"
+	maintenanceTickInterval = time.Minute
+)
+
+var (
+	ErrInvalidMaintenance = errors.New("invalid maintenance window")
+)
+
+func maintenanceWindowFromRow(row monitor_db.MaintenanceWindow) MaintenanceWindow {
+	location, err := time.LoadLocation(row.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	return MaintenanceWindow{
+		Id:         row.ID,
+		ClientId:   row.Clientid,
+		Target:     row.Target,
+		StartsAt:   row.Startsat.In(location),
+		Duration:   time.Duration(row.Durationseconds) * time.Second,
+		Recurrence: row.Recurrence,
+	}
+}
+
+// occurrence
+// Returns start of the latest occurrence that started not after t.
+// Recurring windows are repeated in the timezone of the window,
+// so they keep the local time over daylight saving changes
+func (w MaintenanceWindow) occurrence(t time.Time) (time.Time, bool) {
+	if t.Before(w.StartsAt) {
+		return time.Time{}, false
+	}
+
+	days := 0
+	switch w.Recurrence {
+	case recurrenceDaily:
+		days = 1
+	case recurrenceWeekly:
+		days = 7
+	default:
+		return w.StartsAt, true
+	}
+
+	periods := int(t.Sub(w.StartsAt) / (time.Duration(days) * 24 * time.Hour))
+	start := w.StartsAt.AddDate(0, 0, periods*days)
+	// daylight saving may shift the estimate by an hour
+	for start.After(t) {
+		periods--
+		start = w.StartsAt.AddDate(0, 0, periods*days)
+	}
+	if next := w.StartsAt.AddDate(0, 0, (periods+1)*days); !next.After(t) {
+		start = next
+	}
+
+	return start, true
+}
+
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	start, ok := w.occurrence(t)
+	return ok && t.Before(start.Add(w.Duration))
+}
+
+// endedBetween
+// Reports whether an occurrence of the window ended in (from, to]
+func (w MaintenanceWindow) endedBetween(from, to time.Time) bool {
+	start, ok := w.occurrence(to.Add(-w.Duration))
+	if !ok {
+		return false
+	}
+
+	end := start.Add(w.Duration)
+	return end.After(from) && !end.After(to)
+}
+
+// Finished
+// One-off window is not needed after it ended
+func (w MaintenanceWindow) Finished(t time.Time) bool {
+	return len(w.Recurrence) == 0 && !t.Before(w.StartsAt.Add(w.Duration))
+}
+
+func (w MaintenanceWindow) Matches(endpoint string, tags []string) bool {
+	switch {
+	case w.Target == maintenanceTargetAll:
+		return true
+	case strings.HasPrefix(w.Target, maintenanceTagPrefix):
+		return slices.Contains(tags, strings.TrimPrefix(w.Target, maintenanceTagPrefix))
+	}
+
+	return w.Target == endpoint
+}
+
+func (w MaintenanceWindow) String() string {
+	description := fmt.Sprintf(
+		"#%d %s for %s starting %s",
+		w.Id,
+		w.Target,
+		w.Duration,
+		w.StartsAt.Format("2006-01-02 15:04 MST"),
+	)
+	if len(w.Recurrence) > 0 {
+		description += " " + w.Recurrence
+	}
+
+	return description
+}
+
+// parseMaintenanceSchedule
+// Parses the part of /maintenance command after the target:
+// for 2h [starting [2024-06-01] 22:00] [daily|weekly] [Europe/Berlin]
+//
+// Start time without date is today, or tomorrow
+// when the window would have already ended today
+func parseMaintenanceSchedule(args []string, now time.Time) (time.Time, time.Duration, string, error) {
+	if len(args) < 2 || args[0] != "for" {
+		return time.Time{}, 0, "", fmt.Errorf("%w: duration is missing", ErrInvalidMaintenance)
+	}
+
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		return time.Time{}, 0, "", fmt.Errorf("%w: invalid duration %s", ErrInvalidMaintenance, args[1])
+	}
+	args = args[2:]
+
+	var startDate, startTime, recurrence string
+	location := time.UTC
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "starting" && i+1 < len(args):
+			if _, err := time.Parse(time.DateOnly, args[i+1]); err == nil && i+2 < len(args) {
+				startDate = args[i+1]
+				i++
+			}
+			startTime = args[i+1]
+			i++
+		case args[i] == recurrenceDaily || args[i] == recurrenceWeekly:
+			recurrence = args[i]
+		default:
+			parsedLocation, err := time.LoadLocation(args[i])
+			if err != nil {
+				return time.Time{}, 0, "", fmt.Errorf("%w: unexpected %s", ErrInvalidMaintenance, args[i])
+			}
+			location = parsedLocation
+		}
+	}
+
+	if recurrence == recurrenceDaily && duration >= 24*time.Hour ||
+		recurrence == recurrenceWeekly && duration >= 7*24*time.Hour {
+		return time.Time{}, 0, "", fmt.Errorf("%w: window is longer than its recurrence", ErrInvalidMaintenance)
+	}
+
+	now = now.In(location)
+	if len(startTime) == 0 {
+		return now, duration, recurrence, nil
+	}
+
+	clock, err := time.ParseInLocation("15:04", startTime, location)
+	if err != nil {
+		return time.Time{}, 0, "", fmt.Errorf("%w: invalid start time %s", ErrInvalidMaintenance, startTime)
+	}
+
+	startsAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	if len(startDate) > 0 {
+		date, _ := time.ParseInLocation(time.DateOnly, startDate, location)
+		startsAt = time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	} else if !startsAt.Add(duration).After(now) {
+		startsAt = startsAt.AddDate(0, 0, 1)
+	}
+
+	return startsAt, duration, recurrence, nil
+}
+
+// inMaintenance
+// Checks windows of the client against the endpoint and its tags
+func inMaintenance(ctx context.Context, q *monitor_db.Queries, clientId int64, endpoint string, now time.Time) (bool, error) {
+	rows, err := q.GetClientMaintenanceWindows(ctx, clientId)
+	if err != nil {
+		return false, err
+	}
+
+	tags := endpointTags(endpoint)
+	for _, row := range rows {
+		window := maintenanceWindowFromRow(row)
+		if window.Active(now) && window.Matches(endpoint, tags) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func endpointTags(endpoint string) []string {
+	request, found := botStorage.httpMonitor.GetRequest(endpoint)
+	if !found {
+		return nil
+	}
+
+	return request.TagList()
+}
+
+// SendMaintenanceSummaries
+// When a window ends, the client is told about matching endpoints
+// that are still failing, their alerts were suppressed during the window
+func SendMaintenanceSummaries(ctx context.Context, bot *tele.Bot) {
+	ticker := time.NewTicker(maintenanceTickInterval)
+	defer ticker.Stop()
+
+	lastTick := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			rows, err := botStorage.q.GetMaintenanceWindows(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("load maintenance windows")
+				continue
+			}
+
+			for _, row := range rows {
+				window := maintenanceWindowFromRow(row)
+				if window.endedBetween(lastTick, now) {
+					sendMaintenanceSummary(ctx, bot, window)
+				}
+				if window.Finished(now) {
+					_, err := botStorage.q.RemoveMaintenanceWindow(ctx, monitor_db.RemoveMaintenanceWindowParams{
+						ID:       window.Id,
+						Clientid: window.ClientId,
+					})
+					if err != nil {
+						log.Error().Int64("window", window.Id).Err(err).Msg("remove finished maintenance window")
+					}
+				}
+			}
+			lastTick = now
+		}
+	}
+}
+
+func sendMaintenanceSummary(ctx context.Context, bot *tele.Bot, window MaintenanceWindow) {
+	endpoints, err := botStorage.q.GetUserMonitoredEndpoints(ctx, window.ClientId)
+	if err != nil {
+		log.Error().Int64("clientId", window.ClientId).Err(err).Msg("maintenance summary endpoints")
+		return
+	}
+
+	var failing []string
+	for _, endpoint := range endpoints {
+		if !window.Matches(endpoint, endpointTags(endpoint)) {
+			continue
+		}
+		if err := botStorage.httpMonitor.EndpointError(endpoint); err != nil {
+			failing = append(failing, fmt.Sprintf("  %s: %s", endpoint, err.Error()))
+		}
+	}
+
+	if len(failing) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf(
+		"maintenance of %s has ended, still failing:\n%s",
+		window.Target,
+		strings.Join(failing, "\n"),
+	)
+	if _, err := bot.Send(&tele.User{ID: window.ClientId}, message); err != nil {
+		log.Error().Int64("client", window.ClientId).Err(err).Msg("send maintenance summary")
+	}
+}
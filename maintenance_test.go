@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err.Error())
+	}
+
+	window := MaintenanceWindow{
+		Target:     "tag:db",
+		StartsAt:   time.Date(2024, 3, 20, 22, 0, 0, 0, berlin),
+		Duration:   2 * time.Hour,
+		Recurrence: recurrenceDaily,
+	}
+
+	cases := []struct {
+		at     time.Time
+		active bool
+	}{
+		{at: time.Date(2024, 3, 20, 21, 59, 0, 0, berlin), active: false},
+		{at: time.Date(2024, 3, 20, 23, 0, 0, 0, berlin), active: true},
+		{at: time.Date(2024, 3, 21, 0, 0, 0, 0, berlin), active: false},
+		// after daylight saving change window keeps local time
+		{at: time.Date(2024, 4, 2, 22, 30, 0, 0, berlin), active: true},
+		{at: time.Date(2024, 4, 2, 21, 30, 0, 0, berlin), active: false},
+	}
+	for _, c := range cases {
+		if active := window.Active(c.at); active != c.active {
+			t.Errorf("at %s expected active %t", c.at, c.active)
+		}
+	}
+
+	end := time.Date(2024, 4, 3, 0, 0, 0, 0, berlin)
+	if !window.endedBetween(end.Add(-time.Minute), end) {
+		t.Error("expected window to end at midnight")
+	}
+	if window.endedBetween(end, end.Add(time.Minute)) {
+		t.Error("window end must be reported once")
+	}
+	if window.Finished(end) {
+		t.Error("recurring window is never finished")
+	}
+
+	if !window.Matches("https://db.com", []string{"prod", "db"}) || window.Matches("https://db.com", []string{"prod"}) {
+		t.Error("expected window to match by tag")
+	}
+}
+
+func TestParseMaintenanceSchedule(t *testing.T) {
+	now := time.Date(2024, 6, 1, 23, 30, 0, 0, time.UTC)
+
+	startsAt, duration, recurrence, err := parseMaintenanceSchedule([]string{"for", "2h", "starting", "22:00"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !startsAt.Equal(time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)) || duration != 2*time.Hour || recurrence != "" {
+		t.Errorf("expected window in progress today, got %s for %s", startsAt, duration)
+	}
+
+	startsAt, _, _, err = parseMaintenanceSchedule([]string{"for", "1h", "starting", "22:00", "weekly"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !startsAt.Equal(time.Date(2024, 6, 2, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected window that already ended to start tomorrow, got %s", startsAt)
+	}
+
+	startsAt, _, _, err = parseMaintenanceSchedule(
+		[]string{"for", "30m", "starting", "2024-07-01", "03:00", "Europe/Berlin"},
+		now,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startsAt.Format(time.RFC3339) != "2024-07-01T03:00:00+02:00" {
+		t.Errorf("unexpected start %s", startsAt)
+	}
+
+	for _, args := range [][]string{
+		{"2h"},
+		{"for", "soon"},
+		{"for", "25h", "daily"},
+		{"for", "1h", "starting", "25:00"},
+		{"for", "1h", "tomorrow"},
+	} {
+		if _, _, _, err := parseMaintenanceSchedule(args, now); err == nil {
+			t.Errorf("expected %v to be invalid", args)
+		}
+	}
+}
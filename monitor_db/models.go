@@ -6,24 +6,103 @@ package monitor_db
 
 import (
 	"database/sql"
+	"time"
 )
 
+type CheckResult struct {
+	ID          int64
+	Urlid       int64
+	Checkedat   time.Time
+	Error       string
+	Dnsms       int64
+	Connectms   int64
+	Tlsms       int64
+	Firstbytems int64
+	Transferms  int64
+	Totalms     int64
+	Category    string
+}
+
 type Client struct {
 	Clientid int64
 }
 
+type EndpointSecret struct {
+	Urlid  int64
+	Secret []byte
+}
+
+type EscalationPolicy struct {
+	ID       int64
+	Clientid int64
+	Name     string
+	Target   string
+}
+
+type EscalationStep struct {
+	Policyid     int64
+	Position     int64
+	Chatid       int64
+	Delayseconds int64
+}
+
+type Incident struct {
+	ID         int64
+	Urlid      int64
+	Startedat  time.Time
+	Resolvedat sql.NullTime
+	Error      string
+	Ackedby    string
+	Ackedbyid  int64
+	Ackedat    sql.NullTime
+}
+
+type IncidentEscalation struct {
+	Incidentid int64
+	Policyid   int64
+	Step       int64
+	Escalateat time.Time
+}
+
+type IncidentMessage struct {
+	Incidentid   int64
+	Chatid       int64
+	Messageid    int64
+	Reminders    int64
+	Notifiedat   sql.NullTime
+	Snoozeduntil sql.NullTime
+	Muted        bool
+	Content      string
+	Document     bool
+}
+
+type MaintenanceWindow struct {
+	ID              int64
+	Clientid        int64
+	Target          string
+	Startsat        time.Time
+	Durationseconds int64
+	Recurrence      string
+	Timezone        string
+}
+
 type Request struct {
 	Clientid int64
 	Endpoint string
 }
 
 type UrlsToRequest struct {
-	ID  int64
-	Url string
+	ID       int64
+	Url      string
+	Settings string
 }
 
 type UserUrlSubscription struct {
-	ID       int64
-	Clientid sql.NullInt64
-	Urlid    sql.NullInt64
+	ID               int64
+	Clientid         sql.NullInt64
+	Urlid            sql.NullInt64
+	Categories       string
+	Paused           bool
+	Reminderinterval int64
+	Maxreminders     int64
 }
@@ -8,56 +8,862 @@ package monitor_db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
+const acknowledgeIncident = `-- name: AcknowledgeIncident :execrows
+update incidents set ackedby = ?, ackedbyid = ?, ackedat = ?
+where id = ? and resolvedAt is null and ackedat is null
+`
+
+type AcknowledgeIncidentParams struct {
+	Ackedby   string
+	Ackedbyid int64
+	Ackedat   sql.NullTime
+	ID        int64
+}
+
+func (q *Queries) AcknowledgeIncident(ctx context.Context, arg AcknowledgeIncidentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acknowledgeIncident,
+		arg.Ackedby,
+		arg.Ackedbyid,
+		arg.Ackedat,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const addCheckResult = `-- name: AddCheckResult :exec
+insert into check_results(urlId, checkedAt, error, category, dnsMs, connectMs, tlsMs, firstByteMs, transferMs, totalMs)
+select id, ?, ?, ?, ?, ?, ?, ?, ?, ? from urls_to_request where url = ?
+`
+
+type AddCheckResultParams struct {
+	Checkedat   time.Time
+	Error       string
+	Category    string
+	Dnsms       int64
+	Connectms   int64
+	Tlsms       int64
+	Firstbytems int64
+	Transferms  int64
+	Totalms     int64
+	Url         string
+}
+
+func (q *Queries) AddCheckResult(ctx context.Context, arg AddCheckResultParams) error {
+	_, err := q.db.ExecContext(ctx, addCheckResult,
+		arg.Checkedat,
+		arg.Error,
+		arg.Category,
+		arg.Dnsms,
+		arg.Connectms,
+		arg.Tlsms,
+		arg.Firstbytems,
+		arg.Transferms,
+		arg.Totalms,
+		arg.Url,
+	)
+	return err
+}
+
 const addClient = `-- name: AddClient :exec
 insert into clients(clientId) values(?) on conflict do nothing
 `
 
-func (q *Queries) AddClient(ctx context.Context, clientid int64) error {
-	_, err := q.db.ExecContext(ctx, addClient, clientid)
-	return err
+func (q *Queries) AddClient(ctx context.Context, clientid int64) error {
+	_, err := q.db.ExecContext(ctx, addClient, clientid)
+	return err
+}
+
+const addEscalationMessage = `-- name: AddEscalationMessage :exec
+insert into incident_messages(incidentId, chatId, messageId, notifiedat, content, document)
+values (?, ?, ?, ?, ?, ?)
+on conflict (incidentId, chatId) do nothing
+`
+
+type AddEscalationMessageParams struct {
+	Incidentid int64
+	Chatid     int64
+	Messageid  int64
+	Notifiedat sql.NullTime
+	Content    string
+	Document   bool
+}
+
+func (q *Queries) AddEscalationMessage(ctx context.Context, arg AddEscalationMessageParams) error {
+	_, err := q.db.ExecContext(ctx, addEscalationMessage,
+		arg.Incidentid,
+		arg.Chatid,
+		arg.Messageid,
+		arg.Notifiedat,
+		arg.Content,
+		arg.Document,
+	)
+	return err
+}
+
+const addEscalationPolicy = `-- name: AddEscalationPolicy :one
+insert into escalation_policies(clientId, name, target) values (?, ?, ?)
+on conflict (clientId, name) do update set target = excluded.target
+returning id
+`
+
+type AddEscalationPolicyParams struct {
+	Clientid int64
+	Name     string
+	Target   string
+}
+
+func (q *Queries) AddEscalationPolicy(ctx context.Context, arg AddEscalationPolicyParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, addEscalationPolicy, arg.Clientid, arg.Name, arg.Target)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const addEscalationStep = `-- name: AddEscalationStep :exec
+insert into escalation_steps(policyId, position, chatId, delaySeconds) values (?, ?, ?, ?)
+`
+
+type AddEscalationStepParams struct {
+	Policyid     int64
+	Position     int64
+	Chatid       int64
+	Delayseconds int64
+}
+
+func (q *Queries) AddEscalationStep(ctx context.Context, arg AddEscalationStepParams) error {
+	_, err := q.db.ExecContext(ctx, addEscalationStep,
+		arg.Policyid,
+		arg.Position,
+		arg.Chatid,
+		arg.Delayseconds,
+	)
+	return err
+}
+
+const addIncidentMessage = `-- name: AddIncidentMessage :exec
+insert or replace into incident_messages(incidentId, chatId, messageId, notifiedat, content, document)
+values (?, ?, ?, ?, ?, ?)
+`
+
+type AddIncidentMessageParams struct {
+	Incidentid int64
+	Chatid     int64
+	Messageid  int64
+	Notifiedat sql.NullTime
+	Content    string
+	Document   bool
+}
+
+func (q *Queries) AddIncidentMessage(ctx context.Context, arg AddIncidentMessageParams) error {
+	_, err := q.db.ExecContext(ctx, addIncidentMessage,
+		arg.Incidentid,
+		arg.Chatid,
+		arg.Messageid,
+		arg.Notifiedat,
+		arg.Content,
+		arg.Document,
+	)
+	return err
+}
+
+const addMaintenanceWindow = `-- name: AddMaintenanceWindow :one
+insert into maintenance_windows(clientId, target, startsAt, durationSeconds, recurrence, timezone)
+values (?, ?, ?, ?, ?, ?) returning id
+`
+
+type AddMaintenanceWindowParams struct {
+	Clientid        int64
+	Target          string
+	Startsat        time.Time
+	Durationseconds int64
+	Recurrence      string
+	Timezone        string
+}
+
+func (q *Queries) AddMaintenanceWindow(ctx context.Context, arg AddMaintenanceWindowParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, addMaintenanceWindow,
+		arg.Clientid,
+		arg.Target,
+		arg.Startsat,
+		arg.Durationseconds,
+		arg.Recurrence,
+		arg.Timezone,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const addSubscription = `-- name: AddSubscription :exec
+insert into user_url_subscription (clientId, urlId) values (?, ?)
+`
+
+type AddSubscriptionParams struct {
+	Clientid sql.NullInt64
+	Urlid    sql.NullInt64
+}
+
+func (q *Queries) AddSubscription(ctx context.Context, arg AddSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, addSubscription, arg.Clientid, arg.Urlid)
+	return err
+}
+
+const addUrlToTrack = `-- name: AddUrlToTrack :one
+insert into urls_to_request(url, settings) values (?, ?) returning id
+`
+
+type AddUrlToTrackParams struct {
+	Url      string
+	Settings string
+}
+
+func (q *Queries) AddUrlToTrack(ctx context.Context, arg AddUrlToTrackParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, addUrlToTrack, arg.Url, arg.Settings)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const advanceEscalation = `-- name: AdvanceEscalation :exec
+update incident_escalations set step = ?, escalateAt = ?
+where incidentId = ? and policyId = ?
+`
+
+type AdvanceEscalationParams struct {
+	Step       int64
+	Escalateat time.Time
+	Incidentid int64
+	Policyid   int64
+}
+
+func (q *Queries) AdvanceEscalation(ctx context.Context, arg AdvanceEscalationParams) error {
+	_, err := q.db.ExecContext(ctx, advanceEscalation,
+		arg.Step,
+		arg.Escalateat,
+		arg.Incidentid,
+		arg.Policyid,
+	)
+	return err
+}
+
+const countActiveSubscriptions = `-- name: CountActiveSubscriptions :one
+select count(*)
+from user_url_subscription uus
+inner join urls_to_request ur on uus.urlId = ur.id
+where ur.url = ? and not uus.paused
+`
+
+func (q *Queries) CountActiveSubscriptions(ctx context.Context, url string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveSubscriptions, url)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChatIncidentMessages = `-- name: CountChatIncidentMessages :one
+select count(*) from incident_messages where incidentId = ? and chatId = ?
+`
+
+type CountChatIncidentMessagesParams struct {
+	Incidentid int64
+	Chatid     int64
+}
+
+func (q *Queries) CountChatIncidentMessages(ctx context.Context, arg CountChatIncidentMessagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChatIncidentMessages, arg.Incidentid, arg.Chatid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUrlSubscriptions = `-- name: CountUrlSubscriptions :one
+select count(*) from user_url_subscription where urlId = ?
+`
+
+func (q *Queries) CountUrlSubscriptions(ctx context.Context, urlid sql.NullInt64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUrlSubscriptions, urlid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getCheckHistory = `-- name: GetCheckHistory :many
+select cr.checkedAt, cr.error, cr.category, cr.dnsMs, cr.connectMs, cr.tlsMs, cr.firstByteMs, cr.transferMs, cr.totalMs
+from check_results cr
+inner join urls_to_request ur on cr.urlId = ur.id
+where ur.url = ?
+order by cr.checkedAt desc
+limit ?
+`
+
+type GetCheckHistoryParams struct {
+	Url   string
+	Limit int64
+}
+
+type GetCheckHistoryRow struct {
+	Checkedat   time.Time
+	Error       string
+	Category    string
+	Dnsms       int64
+	Connectms   int64
+	Tlsms       int64
+	Firstbytems int64
+	Transferms  int64
+	Totalms     int64
+}
+
+func (q *Queries) GetCheckHistory(ctx context.Context, arg GetCheckHistoryParams) ([]GetCheckHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCheckHistory, arg.Url, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCheckHistoryRow
+	for rows.Next() {
+		var i GetCheckHistoryRow
+		if err := rows.Scan(
+			&i.Checkedat,
+			&i.Error,
+			&i.Category,
+			&i.Dnsms,
+			&i.Connectms,
+			&i.Tlsms,
+			&i.Firstbytems,
+			&i.Transferms,
+			&i.Totalms,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClientEscalationPolicies = `-- name: GetClientEscalationPolicies :many
+select ep.id, ep.name, ep.target, es.chatId, es.delaySeconds
+from escalation_policies ep
+inner join escalation_steps es on es.policyId = ep.id
+where ep.clientId = ?
+order by ep.name, es.position
+`
+
+type GetClientEscalationPoliciesRow struct {
+	ID           int64
+	Name         string
+	Target       string
+	Chatid       int64
+	Delayseconds int64
+}
+
+func (q *Queries) GetClientEscalationPolicies(ctx context.Context, clientid int64) ([]GetClientEscalationPoliciesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClientEscalationPolicies, clientid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetClientEscalationPoliciesRow
+	for rows.Next() {
+		var i GetClientEscalationPoliciesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Target,
+			&i.Chatid,
+			&i.Delayseconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getClientMaintenanceWindows = `-- name: GetClientMaintenanceWindows :many
+select id, clientid, target, startsat, durationseconds, recurrence, timezone from maintenance_windows where clientId = ? order by startsAt
+`
+
+func (q *Queries) GetClientMaintenanceWindows(ctx context.Context, clientid int64) ([]MaintenanceWindow, error) {
+	rows, err := q.db.QueryContext(ctx, getClientMaintenanceWindows, clientid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MaintenanceWindow
+	for rows.Next() {
+		var i MaintenanceWindow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Clientid,
+			&i.Target,
+			&i.Startsat,
+			&i.Durationseconds,
+			&i.Recurrence,
+			&i.Timezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEndpointEscalationPolicies = `-- name: GetEndpointEscalationPolicies :many
+select ep.id, ep.clientId, ep.target
+from escalation_policies ep
+where ep.clientId in (
+    select uus.clientId
+    from user_url_subscription uus
+    inner join urls_to_request ur on uus.urlId = ur.id
+    where ur.url = ? and not uus.paused
+)
+`
+
+type GetEndpointEscalationPoliciesRow struct {
+	ID       int64
+	Clientid int64
+	Target   string
+}
+
+func (q *Queries) GetEndpointEscalationPolicies(ctx context.Context, url string) ([]GetEndpointEscalationPoliciesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEndpointEscalationPolicies, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEndpointEscalationPoliciesRow
+	for rows.Next() {
+		var i GetEndpointEscalationPoliciesRow
+		if err := rows.Scan(&i.ID, &i.Clientid, &i.Target); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEndpointSecret = `-- name: GetEndpointSecret :one
+select secret from endpoint_secrets where urlId = ?
+`
+
+func (q *Queries) GetEndpointSecret(ctx context.Context, urlid int64) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getEndpointSecret, urlid)
+	var secret []byte
+	err := row.Scan(&secret)
+	return secret, err
+}
+
+const getEndpointSettings = `-- name: GetEndpointSettings :one
+select settings from urls_to_request where url = ?
+`
+
+func (q *Queries) GetEndpointSettings(ctx context.Context, url string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getEndpointSettings, url)
+	var settings string
+	err := row.Scan(&settings)
+	return settings, err
+}
+
+const getEndpointToMonitor = `-- name: GetEndpointToMonitor :one
+select ur.id, ur.url, ur.settings, es.secret
+from urls_to_request ur
+left join endpoint_secrets es on es.urlId = ur.id
+where ur.url = ?
+`
+
+type GetEndpointToMonitorRow struct {
+	ID       int64
+	Url      string
+	Settings string
+	Secret   []byte
+}
+
+func (q *Queries) GetEndpointToMonitor(ctx context.Context, url string) (GetEndpointToMonitorRow, error) {
+	row := q.db.QueryRowContext(ctx, getEndpointToMonitor, url)
+	var i GetEndpointToMonitorRow
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Settings,
+		&i.Secret,
+	)
+	return i, err
+}
+
+const getEndpointsToMonitor = `-- name: GetEndpointsToMonitor :many
+select ur.id, ur.url, ur.settings, es.secret
+from urls_to_request ur
+left join endpoint_secrets es on es.urlId = ur.id
+where exists (
+    select 1 from user_url_subscription uus where uus.urlId = ur.id and not uus.paused
+)
+`
+
+type GetEndpointsToMonitorRow struct {
+	ID       int64
+	Url      string
+	Settings string
+	Secret   []byte
+}
+
+func (q *Queries) GetEndpointsToMonitor(ctx context.Context) ([]GetEndpointsToMonitorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEndpointsToMonitor)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEndpointsToMonitorRow
+	for rows.Next() {
+		var i GetEndpointsToMonitorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Settings,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEscalationPolicyId = `-- name: GetEscalationPolicyId :one
+select id from escalation_policies where clientId = ? and name = ?
+`
+
+type GetEscalationPolicyIdParams struct {
+	Clientid int64
+	Name     string
+}
+
+func (q *Queries) GetEscalationPolicyId(ctx context.Context, arg GetEscalationPolicyIdParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getEscalationPolicyId, arg.Clientid, arg.Name)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getEscalationSteps = `-- name: GetEscalationSteps :many
+select chatId, delaySeconds from escalation_steps where policyId = ? order by position
+`
+
+type GetEscalationStepsRow struct {
+	Chatid       int64
+	Delayseconds int64
+}
+
+func (q *Queries) GetEscalationSteps(ctx context.Context, policyid int64) ([]GetEscalationStepsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEscalationSteps, policyid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEscalationStepsRow
+	for rows.Next() {
+		var i GetEscalationStepsRow
+		if err := rows.Scan(&i.Chatid, &i.Delayseconds); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEscalations = `-- name: GetEscalations :many
+select ie.incidentId, ie.policyId, ie.step, ie.escalateAt, ep.name,
+       i.startedAt, i.error, i.ackedat, i.resolvedAt, ur.url
+from incident_escalations ie
+inner join incidents i on ie.incidentId = i.id
+inner join urls_to_request ur on i.urlId = ur.id
+inner join escalation_policies ep on ie.policyId = ep.id
+where exists (
+    select 1 from user_url_subscription uus
+    where uus.urlId = i.urlId and uus.clientId = ep.clientId and not uus.paused
+)
+`
+
+type GetEscalationsRow struct {
+	Incidentid int64
+	Policyid   int64
+	Step       int64
+	Escalateat time.Time
+	Name       string
+	Startedat  time.Time
+	Error      string
+	Ackedat    sql.NullTime
+	Resolvedat sql.NullTime
+	Url        string
+}
+
+func (q *Queries) GetEscalations(ctx context.Context) ([]GetEscalationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEscalations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEscalationsRow
+	for rows.Next() {
+		var i GetEscalationsRow
+		if err := rows.Scan(
+			&i.Incidentid,
+			&i.Policyid,
+			&i.Step,
+			&i.Escalateat,
+			&i.Name,
+			&i.Startedat,
+			&i.Error,
+			&i.Ackedat,
+			&i.Resolvedat,
+			&i.Url,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIncidentMessages = `-- name: GetIncidentMessages :many
+select chatId, messageId, content, document from incident_messages where incidentId = ?
+`
+
+type GetIncidentMessagesRow struct {
+	Chatid    int64
+	Messageid int64
+	Content   string
+	Document  bool
 }
 
-const addSubscription = `-- name: AddSubscription :exec
-insert into user_url_subscription (clientId, urlId) values (?, ?)
+func (q *Queries) GetIncidentMessages(ctx context.Context, incidentid int64) ([]GetIncidentMessagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getIncidentMessages, incidentid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIncidentMessagesRow
+	for rows.Next() {
+		var i GetIncidentMessagesRow
+		if err := rows.Scan(
+			&i.Chatid,
+			&i.Messageid,
+			&i.Content,
+			&i.Document,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMaintenanceWindows = `-- name: GetMaintenanceWindows :many
+select id, clientid, target, startsat, durationseconds, recurrence, timezone from maintenance_windows
 `
 
-type AddSubscriptionParams struct {
-	Clientid sql.NullInt64
-	Urlid    sql.NullInt64
+func (q *Queries) GetMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	rows, err := q.db.QueryContext(ctx, getMaintenanceWindows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MaintenanceWindow
+	for rows.Next() {
+		var i MaintenanceWindow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Clientid,
+			&i.Target,
+			&i.Startsat,
+			&i.Durationseconds,
+			&i.Recurrence,
+			&i.Timezone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) AddSubscription(ctx context.Context, arg AddSubscriptionParams) error {
-	_, err := q.db.ExecContext(ctx, addSubscription, arg.Clientid, arg.Urlid)
-	return err
+const getOpenIncident = `-- name: GetOpenIncident :one
+select i.id, i.startedAt, i.error
+from incidents i
+inner join urls_to_request ur on i.urlId = ur.id
+where ur.url = ? and i.resolvedAt is null
+order by i.id desc
+limit 1
+`
+
+type GetOpenIncidentRow struct {
+	ID        int64
+	Startedat time.Time
+	Error     string
 }
 
-const addUrlToTrack = `-- name: AddUrlToTrack :one
-insert into urls_to_request(url) values (?) returning id
+func (q *Queries) GetOpenIncident(ctx context.Context, url string) (GetOpenIncidentRow, error) {
+	row := q.db.QueryRowContext(ctx, getOpenIncident, url)
+	var i GetOpenIncidentRow
+	err := row.Scan(&i.ID, &i.Startedat, &i.Error)
+	return i, err
+}
+
+const getOpenIncidents = `-- name: GetOpenIncidents :many
+select ur.url, i.startedAt, i.error, (
+    select count(*) from check_results cr
+    where cr.urlId = i.urlId and cr.checkedAt >= i.startedAt and cr.error != ''
+) as failedChecks
+from incidents i
+inner join urls_to_request ur on i.urlId = ur.id
+where i.resolvedAt is null
 `
 
-func (q *Queries) AddUrlToTrack(ctx context.Context, url string) (int64, error) {
-	row := q.db.QueryRowContext(ctx, addUrlToTrack, url)
-	var id int64
-	err := row.Scan(&id)
-	return id, err
+type GetOpenIncidentsRow struct {
+	Url          string
+	Startedat    time.Time
+	Error        string
+	Failedchecks int64
 }
 
-const getEndpointsToMonitor = `-- name: GetEndpointsToMonitor :many
-select id, url from urls_to_request
+func (q *Queries) GetOpenIncidents(ctx context.Context) ([]GetOpenIncidentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOpenIncidents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOpenIncidentsRow
+	for rows.Next() {
+		var i GetOpenIncidentsRow
+		if err := rows.Scan(
+			&i.Url,
+			&i.Startedat,
+			&i.Error,
+			&i.Failedchecks,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPendingReminders = `-- name: GetPendingReminders :many
+select im.incidentId, im.chatId, im.messageId, im.reminders, im.notifiedat, im.snoozeduntil,
+       i.startedAt, i.error, ur.url, uus.reminderinterval, uus.maxreminders
+from incident_messages im
+inner join incidents i on im.incidentId = i.id
+inner join urls_to_request ur on i.urlId = ur.id
+inner join user_url_subscription uus on uus.urlId = i.urlId and uus.clientId = im.chatId
+where i.resolvedAt is null
+  and i.ackedat is null
+  and uus.reminderinterval > 0
+  and im.reminders < uus.maxreminders
+  and not im.muted
+  and not uus.paused
 `
 
-func (q *Queries) GetEndpointsToMonitor(ctx context.Context) ([]UrlsToRequest, error) {
-	rows, err := q.db.QueryContext(ctx, getEndpointsToMonitor)
+type GetPendingRemindersRow struct {
+	Incidentid       int64
+	Chatid           int64
+	Messageid        int64
+	Reminders        int64
+	Notifiedat       sql.NullTime
+	Snoozeduntil     sql.NullTime
+	Startedat        time.Time
+	Error            string
+	Url              string
+	Reminderinterval int64
+	Maxreminders     int64
+}
+
+func (q *Queries) GetPendingReminders(ctx context.Context) ([]GetPendingRemindersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPendingReminders)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []UrlsToRequest
+	var items []GetPendingRemindersRow
 	for rows.Next() {
-		var i UrlsToRequest
-		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+		var i GetPendingRemindersRow
+		if err := rows.Scan(
+			&i.Incidentid,
+			&i.Chatid,
+			&i.Messageid,
+			&i.Reminders,
+			&i.Notifiedat,
+			&i.Snoozeduntil,
+			&i.Startedat,
+			&i.Error,
+			&i.Url,
+			&i.Reminderinterval,
+			&i.Maxreminders,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -88,6 +894,7 @@ from urls_to_request ur
 inner join user_url_subscription uus on ur.id = uus.urlId
 inner join clients c on uus.clientId = c.clientId
 where c.clientId = ?
+order by uus.id
 `
 
 func (q *Queries) GetUserMonitoredEndpoints(ctx context.Context, clientid int64) ([]string, error) {
@@ -113,27 +920,68 @@ func (q *Queries) GetUserMonitoredEndpoints(ctx context.Context, clientid int64)
 	return items, nil
 }
 
+const getUserSubscriptions = `-- name: GetUserSubscriptions :many
+select ur.url, uus.paused
+from urls_to_request ur
+inner join user_url_subscription uus on ur.id = uus.urlId
+where uus.clientId = ?
+order by uus.id
+`
+
+type GetUserSubscriptionsRow struct {
+	Url    string
+	Paused bool
+}
+
+func (q *Queries) GetUserSubscriptions(ctx context.Context, clientid sql.NullInt64) ([]GetUserSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSubscriptions, clientid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserSubscriptionsRow
+	for rows.Next() {
+		var i GetUserSubscriptionsRow
+		if err := rows.Scan(&i.Url, &i.Paused); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUsersToNotify = `-- name: GetUsersToNotify :many
-select c.clientId
+select c.clientId, uus.categories
 from clients c
 inner join user_url_subscription uus on c.clientId = uus.clientId
 inner join urls_to_request ur on uus.urlId = ur.id
-where ur.url = ?
+where ur.url = ? and not uus.paused
 `
 
-func (q *Queries) GetUsersToNotify(ctx context.Context, url string) ([]int64, error) {
+type GetUsersToNotifyRow struct {
+	Clientid   int64
+	Categories string
+}
+
+func (q *Queries) GetUsersToNotify(ctx context.Context, url string) ([]GetUsersToNotifyRow, error) {
 	rows, err := q.db.QueryContext(ctx, getUsersToNotify, url)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []int64
+	var items []GetUsersToNotifyRow
 	for rows.Next() {
-		var clientid int64
-		if err := rows.Scan(&clientid); err != nil {
+		var i GetUsersToNotifyRow
+		if err := rows.Scan(&i.Clientid, &i.Categories); err != nil {
 			return nil, err
 		}
-		items = append(items, clientid)
+		items = append(items, i)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -144,6 +992,77 @@ func (q *Queries) GetUsersToNotify(ctx context.Context, url string) ([]int64, er
 	return items, nil
 }
 
+const muteIncidentMessage = `-- name: MuteIncidentMessage :execrows
+update incident_messages set muted = true
+where incidentId = ? and chatId = ?
+`
+
+type MuteIncidentMessageParams struct {
+	Incidentid int64
+	Chatid     int64
+}
+
+func (q *Queries) MuteIncidentMessage(ctx context.Context, arg MuteIncidentMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, muteIncidentMessage, arg.Incidentid, arg.Chatid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const openIncident = `-- name: OpenIncident :one
+insert into incidents(urlId, startedAt, error)
+select id, ?, ? from urls_to_request where url = ?
+returning id
+`
+
+type OpenIncidentParams struct {
+	Startedat time.Time
+	Error     string
+	Url       string
+}
+
+func (q *Queries) OpenIncident(ctx context.Context, arg OpenIncidentParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, openIncident, arg.Startedat, arg.Error, arg.Url)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const recordReminder = `-- name: RecordReminder :exec
+update incident_messages set reminders = reminders + 1, notifiedat = ?
+where incidentId = ? and chatId = ?
+`
+
+type RecordReminderParams struct {
+	Notifiedat sql.NullTime
+	Incidentid int64
+	Chatid     int64
+}
+
+func (q *Queries) RecordReminder(ctx context.Context, arg RecordReminderParams) error {
+	_, err := q.db.ExecContext(ctx, recordReminder, arg.Notifiedat, arg.Incidentid, arg.Chatid)
+	return err
+}
+
+const removeCheckResults = `-- name: RemoveCheckResults :exec
+delete from check_results where urlId = ?
+`
+
+func (q *Queries) RemoveCheckResults(ctx context.Context, urlid int64) error {
+	_, err := q.db.ExecContext(ctx, removeCheckResults, urlid)
+	return err
+}
+
+const removeCheckResultsBefore = `-- name: RemoveCheckResultsBefore :exec
+delete from check_results where checkedAt < ?
+`
+
+func (q *Queries) RemoveCheckResultsBefore(ctx context.Context, checkedat time.Time) error {
+	_, err := q.db.ExecContext(ctx, removeCheckResultsBefore, checkedat)
+	return err
+}
+
 const removeClient = `-- name: RemoveClient :exec
 delete from clients where clientId = ?
 `
@@ -153,6 +1072,104 @@ func (q *Queries) RemoveClient(ctx context.Context, clientid int64) error {
 	return err
 }
 
+const removeEndpointSecret = `-- name: RemoveEndpointSecret :exec
+delete from endpoint_secrets where urlId = ?
+`
+
+func (q *Queries) RemoveEndpointSecret(ctx context.Context, urlid int64) error {
+	_, err := q.db.ExecContext(ctx, removeEndpointSecret, urlid)
+	return err
+}
+
+const removeEscalationPolicy = `-- name: RemoveEscalationPolicy :exec
+delete from escalation_policies where id = ?
+`
+
+func (q *Queries) RemoveEscalationPolicy(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, removeEscalationPolicy, id)
+	return err
+}
+
+const removeEscalationSteps = `-- name: RemoveEscalationSteps :exec
+delete from escalation_steps where policyId = ?
+`
+
+func (q *Queries) RemoveEscalationSteps(ctx context.Context, policyid int64) error {
+	_, err := q.db.ExecContext(ctx, removeEscalationSteps, policyid)
+	return err
+}
+
+const removeIncidentEscalations = `-- name: RemoveIncidentEscalations :exec
+delete from incident_escalations where incidentId in (select id from incidents where urlId = ?)
+`
+
+func (q *Queries) RemoveIncidentEscalations(ctx context.Context, urlid int64) error {
+	_, err := q.db.ExecContext(ctx, removeIncidentEscalations, urlid)
+	return err
+}
+
+const removeIncidentMessages = `-- name: RemoveIncidentMessages :exec
+delete from incident_messages where incidentId in (select id from incidents where urlId = ?)
+`
+
+func (q *Queries) RemoveIncidentMessages(ctx context.Context, urlid int64) error {
+	_, err := q.db.ExecContext(ctx, removeIncidentMessages, urlid)
+	return err
+}
+
+const removeIncidentMessagesResolvedBefore = `-- name: RemoveIncidentMessagesResolvedBefore :exec
+delete from incident_messages where incidentId in (select id from incidents where resolvedAt < ?)
+`
+
+func (q *Queries) RemoveIncidentMessagesResolvedBefore(ctx context.Context, resolvedat sql.NullTime) error {
+	_, err := q.db.ExecContext(ctx, removeIncidentMessagesResolvedBefore, resolvedat)
+	return err
+}
+
+const removeIncidents = `-- name: RemoveIncidents :exec
+delete from incidents where urlId = ?
+`
+
+func (q *Queries) RemoveIncidents(ctx context.Context, urlid int64) error {
+	_, err := q.db.ExecContext(ctx, removeIncidents, urlid)
+	return err
+}
+
+const removeIncidentsResolvedBefore = `-- name: RemoveIncidentsResolvedBefore :exec
+delete from incidents where resolvedAt < ?
+`
+
+func (q *Queries) RemoveIncidentsResolvedBefore(ctx context.Context, resolvedat sql.NullTime) error {
+	_, err := q.db.ExecContext(ctx, removeIncidentsResolvedBefore, resolvedat)
+	return err
+}
+
+const removeMaintenanceWindow = `-- name: RemoveMaintenanceWindow :execrows
+delete from maintenance_windows where id = ? and clientId = ?
+`
+
+type RemoveMaintenanceWindowParams struct {
+	ID       int64
+	Clientid int64
+}
+
+func (q *Queries) RemoveMaintenanceWindow(ctx context.Context, arg RemoveMaintenanceWindowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeMaintenanceWindow, arg.ID, arg.Clientid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removePolicyEscalations = `-- name: RemovePolicyEscalations :exec
+delete from incident_escalations where policyId = ?
+`
+
+func (q *Queries) RemovePolicyEscalations(ctx context.Context, policyid int64) error {
+	_, err := q.db.ExecContext(ctx, removePolicyEscalations, policyid)
+	return err
+}
+
 const removeSubscription = `-- name: RemoveSubscription :exec
 delete from user_url_subscription where clientId = ? and urlId = ?
 `
@@ -175,3 +1192,148 @@ func (q *Queries) RemoveUrlToTrack(ctx context.Context, url string) error {
 	_, err := q.db.ExecContext(ctx, removeUrlToTrack, url)
 	return err
 }
+
+const resolveIncident = `-- name: ResolveIncident :exec
+update incidents set resolvedAt = ? where id = ?
+`
+
+type ResolveIncidentParams struct {
+	Resolvedat sql.NullTime
+	ID         int64
+}
+
+func (q *Queries) ResolveIncident(ctx context.Context, arg ResolveIncidentParams) error {
+	_, err := q.db.ExecContext(ctx, resolveIncident, arg.Resolvedat, arg.ID)
+	return err
+}
+
+const setAlertCategories = `-- name: SetAlertCategories :exec
+update user_url_subscription set categories = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?)
+`
+
+type SetAlertCategoriesParams struct {
+	Categories string
+	Clientid   sql.NullInt64
+	Url        string
+}
+
+func (q *Queries) SetAlertCategories(ctx context.Context, arg SetAlertCategoriesParams) error {
+	_, err := q.db.ExecContext(ctx, setAlertCategories, arg.Categories, arg.Clientid, arg.Url)
+	return err
+}
+
+const setEndpointSecret = `-- name: SetEndpointSecret :exec
+insert into endpoint_secrets(urlId, secret) values (?, ?)
+on conflict(urlId) do update set secret = excluded.secret
+`
+
+type SetEndpointSecretParams struct {
+	Urlid  int64
+	Secret []byte
+}
+
+func (q *Queries) SetEndpointSecret(ctx context.Context, arg SetEndpointSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setEndpointSecret, arg.Urlid, arg.Secret)
+	return err
+}
+
+const setReminder = `-- name: SetReminder :exec
+update user_url_subscription set reminderinterval = ?, maxreminders = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?)
+`
+
+type SetReminderParams struct {
+	Reminderinterval int64
+	Maxreminders     int64
+	Clientid         sql.NullInt64
+	Url              string
+}
+
+func (q *Queries) SetReminder(ctx context.Context, arg SetReminderParams) error {
+	_, err := q.db.ExecContext(ctx, setReminder,
+		arg.Reminderinterval,
+		arg.Maxreminders,
+		arg.Clientid,
+		arg.Url,
+	)
+	return err
+}
+
+const setSubscriptionPaused = `-- name: SetSubscriptionPaused :exec
+update user_url_subscription set paused = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?)
+`
+
+type SetSubscriptionPausedParams struct {
+	Paused   bool
+	Clientid sql.NullInt64
+	Url      string
+}
+
+func (q *Queries) SetSubscriptionPaused(ctx context.Context, arg SetSubscriptionPausedParams) error {
+	_, err := q.db.ExecContext(ctx, setSubscriptionPaused, arg.Paused, arg.Clientid, arg.Url)
+	return err
+}
+
+const snoozeIncidentMessage = `-- name: SnoozeIncidentMessage :execrows
+update incident_messages set snoozeduntil = ?
+where incidentId = ? and chatId = ?
+`
+
+type SnoozeIncidentMessageParams struct {
+	Snoozeduntil sql.NullTime
+	Incidentid   int64
+	Chatid       int64
+}
+
+func (q *Queries) SnoozeIncidentMessage(ctx context.Context, arg SnoozeIncidentMessageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, snoozeIncidentMessage, arg.Snoozeduntil, arg.Incidentid, arg.Chatid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const startEscalation = `-- name: StartEscalation :exec
+insert or ignore into incident_escalations(incidentId, policyId, step, escalateAt) values (?, ?, 0, ?)
+`
+
+type StartEscalationParams struct {
+	Incidentid int64
+	Policyid   int64
+	Escalateat time.Time
+}
+
+func (q *Queries) StartEscalation(ctx context.Context, arg StartEscalationParams) error {
+	_, err := q.db.ExecContext(ctx, startEscalation, arg.Incidentid, arg.Policyid, arg.Escalateat)
+	return err
+}
+
+const stopEscalation = `-- name: StopEscalation :exec
+delete from incident_escalations where incidentId = ? and policyId = ?
+`
+
+type StopEscalationParams struct {
+	Incidentid int64
+	Policyid   int64
+}
+
+func (q *Queries) StopEscalation(ctx context.Context, arg StopEscalationParams) error {
+	_, err := q.db.ExecContext(ctx, stopEscalation, arg.Incidentid, arg.Policyid)
+	return err
+}
+
+const updateEndpointSettings = `-- name: UpdateEndpointSettings :exec
+update urls_to_request set settings = ? where url = ?
+`
+
+type UpdateEndpointSettingsParams struct {
+	Settings string
+	Url      string
+}
+
+func (q *Queries) UpdateEndpointSettings(ctx context.Context, arg UpdateEndpointSettingsParams) error {
+	_, err := q.db.ExecContext(ctx, updateEndpointSettings, arg.Settings, arg.Url)
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+
+	pingReplyTimeout = time.Second
+)
+
+var (
+	pingPayload = []byte("telegram-http-monitor")
+)
+
+// pingHost
+// Uses datagram icmp sockets that linux allows for groups
+// listed in net.ipv4.ping_group_range, so no raw sockets
+// and no root privileges are needed.
+//
+// Requests that were not sent before the context deadline
+// are counted as lost
+func pingHost(ctx context.Context, host string, count int, interval time.Duration) (PingStatistics, error) {
+	stats := PingStatistics{Sent: count}
+
+	ip, err := resolvePingAddress(ctx, host)
+	if err != nil {
+		return stats, err
+	}
+
+	conn, err := listenPing(ip)
+	if err != nil {
+		return stats, err
+	}
+	defer conn.Close()
+
+	requestType, replyType := byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		requestType, replyType = icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	reply := make([]byte, 1500)
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+				return stats, nil
+			case <-time.After(interval):
+			}
+		}
+
+		sentAt := time.Now()
+		readDeadline := sentAt.Add(pingReplyTimeout)
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+
+		_, err := conn.WriteTo(marshalEchoRequest(requestType, seq), &net.UDPAddr{IP: ip})
+		if err != nil {
+			return stats, err
+		}
+
+		received, err := waitForEchoReply(conn, reply, replyType, seq, readDeadline)
+		if err != nil {
+			return stats, err
+		}
+		if received {
+			stats.addRtt(time.Since(sentAt))
+		}
+	}
+
+	return stats, nil
+}
+
+func resolvePingAddress(ctx context.Context, host string) (net.IP, error) {
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+		if address.IP.To4() != nil {
+			return address.IP, nil
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("host %s has no ip addresses", host)
+	}
+
+	return addresses[0].IP, nil
+}
+
+func listenPing(ip net.IP) (net.PacketConn, error) {
+	family, protocol := syscall.AF_INET, syscall.IPPROTO_ICMP
+	var address syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if ip.To4() == nil {
+		family, protocol = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		address = &syscall.SockaddrInet6{}
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, protocol)
+	if errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM) {
+		return nil, fmt.Errorf("%w: %w", ErrPingNotPermitted, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Bind(fd, address); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	socketFile := os.NewFile(uintptr(fd), "icmp")
+	defer socketFile.Close()
+
+	return net.FilePacketConn(socketFile)
+}
+
+// marshalEchoRequest
+// Identifier and checksum are left empty,
+// kernel fills them in for datagram icmp sockets
+func marshalEchoRequest(requestType byte, seq int) []byte {
+	message := make([]byte, 8+len(pingPayload))
+	message[0] = requestType
+	binary.BigEndian.PutUint16(message[6:8], uint16(seq))
+	copy(message[8:], pingPayload)
+
+	return message
+}
+
+func waitForEchoReply(conn net.PacketConn, buffer []byte, replyType byte, seq int, deadline time.Time) (bool, error) {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return false, err
+	}
+
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// replies to previous requests that arrived late are skipped
+		if n >= 8 && buffer[0] == replyType && int(binary.BigEndian.Uint16(buffer[6:8])) == seq {
+			return true, nil
+		}
+	}
+}
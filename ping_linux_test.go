@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPingLocalhost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := pingHost(ctx, "127.0.0.1", 3, 10*time.Millisecond)
+	if errors.Is(err, ErrPingNotPermitted) {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Sent != 3 || stats.Received != 3 {
+		t.Errorf("expected 3/3 replies, received %d/%d", stats.Received, stats.Sent)
+	}
+	if stats.PacketLoss() != 0 {
+		t.Errorf("expected no packet loss, got %.0f%%", stats.PacketLoss())
+	}
+	if stats.MinRtt > stats.AvgRtt || stats.AvgRtt > stats.MaxRtt {
+		t.Errorf("inconsistent rtt: min %s, avg %s, max %s", stats.MinRtt, stats.AvgRtt, stats.MaxRtt)
+	}
+}
+
+func TestCheckPingThresholds(t *testing.T) {
+	request := &EndpointRequest{Endpoint: "ping://127.0.0.1", PingCount: 2}
+	err := checkPing("127.0.0.1", request, 5*time.Second)
+	if errors.Is(err, ErrPingNotPermitted) {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Fatalf("expected localhost to pass default thresholds, got %s", err)
+	}
+
+	request.MaxRtt = time.Nanosecond
+	if err := checkPing("127.0.0.1", request, 5*time.Second); err == nil {
+		t.Error("expected rtt threshold to fail")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	PingStatistics struct {
+		Sent     int
+		Received int
+		MinRtt   time.Duration
+		AvgRtt   time.Duration
+		MaxRtt   time.Duration
+	}
+)
+
+const (
+	defaultPingCount     = 5
+	defaultPingInterval  = 200 * time.Millisecond
+	defaultMaxPacketLoss = 20
+)
+
+var (
+	ErrPingNotPermitted = errors.New("unprivileged icmp sockets are not permitted, check net.ipv4.ping_group_range")
+	ErrPingUnsupported  = errors.New("icmp ping is not supported on this platform")
+)
+
+// PacketLoss
+// Percentage of echo requests that were left without a reply
+func (s PingStatistics) PacketLoss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+
+	return float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+}
+
+func (s *PingStatistics) addRtt(rtt time.Duration) {
+	if s.Received == 0 || rtt < s.MinRtt {
+		s.MinRtt = rtt
+	}
+	if rtt > s.MaxRtt {
+		s.MaxRtt = rtt
+	}
+
+	s.AvgRtt = (s.AvgRtt*time.Duration(s.Received) + rtt) / time.Duration(s.Received+1)
+	s.Received += 1
+}
+
+// checkPing
+// Sends a small burst of echo requests to the host and compares
+// packet loss and average rtt with the endpoint thresholds.
+// Unset packet loss threshold defaults to 20%, unset rtt threshold is not checked
+func checkPing(host string, r *EndpointRequest, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	count := r.PingCount
+	if count <= 0 {
+		count = defaultPingCount
+	}
+
+	maxPacketLoss := r.MaxPacketLoss
+	if maxPacketLoss <= 0 {
+		maxPacketLoss = defaultMaxPacketLoss
+	}
+
+	stats, err := pingHost(ctx, host, count, defaultPingInterval)
+	if err != nil {
+		return err
+	}
+
+	if stats.Received == 0 {
+		return fmt.Errorf("host %s did not reply to any of %d echo requests", host, stats.Sent)
+	}
+
+	if stats.PacketLoss() > maxPacketLoss {
+		return fmt.Errorf(
+			"packet loss %.0f%% (%d/%d replies) exceeds %.0f%%",
+			stats.PacketLoss(),
+			stats.Received,
+			stats.Sent,
+			maxPacketLoss,
+		)
+	}
+
+	if r.MaxRtt > 0 && stats.AvgRtt > r.MaxRtt {
+		return fmt.Errorf("average rtt %s exceeds %s", stats.AvgRtt, r.MaxRtt)
+	}
+
+	return nil
+}
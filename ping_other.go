@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+func pingHost(_ context.Context, _ string, count int, _ time.Duration) (PingStatistics, error) {
+	return PingStatistics{Sent: count}, ErrPingUnsupported
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v3"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"time"
+)
+
+const (
+	reminderTickInterval = time.Minute
+)
+
+// SendReminders
+// Repeats alerts of endpoints that are still down as replies
+// to the original alert, every reminder interval of the subscription
+// until its max reminders are sent, the endpoint recovers
+// or the incident is acknowledged
+func SendReminders(ctx context.Context, bot *tele.Bot) {
+	ticker := time.NewTicker(reminderTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reminders, err := botStorage.q.GetPendingReminders(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("load pending reminders")
+				continue
+			}
+
+			for _, reminder := range reminders {
+				if reminderDue(reminder, now) {
+					sendReminder(ctx, bot, reminder, now)
+				}
+			}
+		}
+	}
+}
+
+func reminderDue(reminder monitor_db.GetPendingRemindersRow, now time.Time) bool {
+	if reminder.Snoozeduntil.Valid && now.Before(reminder.Snoozeduntil.Time) {
+		return false
+	}
+
+	interval := time.Duration(reminder.Reminderinterval) * time.Second
+	notifiedAt := reminder.Startedat
+	if reminder.Notifiedat.Valid {
+		notifiedAt = reminder.Notifiedat.Time
+	}
+
+	return !now.Before(notifiedAt.Add(interval))
+}
+
+func sendReminder(ctx context.Context, bot *tele.Bot, reminder monitor_db.GetPendingRemindersRow, now time.Time) {
+	silenced, err := inMaintenance(ctx, botStorage.q, reminder.Chatid, reminder.Url, now)
+	if err != nil {
+		log.Error().Int64("client", reminder.Chatid).Err(err).Msg("check maintenance windows")
+	}
+	if silenced {
+		return
+	}
+
+	chat := &tele.Chat{ID: reminder.Chatid}
+	_, err = bot.Send(chat, reminderMessage(reminder, now), &tele.SendOptions{
+		ReplyTo:           &tele.Message{ID: int(reminder.Messageid), Chat: chat},
+		AllowWithoutReply: true,
+	})
+	if err != nil {
+		log.Error().Int64("client", reminder.Chatid).Err(err).Msg("send reminder")
+		return
+	}
+
+	err = botStorage.q.RecordReminder(ctx, monitor_db.RecordReminderParams{
+		Notifiedat: sql.NullTime{Time: now, Valid: true},
+		Incidentid: reminder.Incidentid,
+		Chatid:     reminder.Chatid,
+	})
+	if err != nil {
+		log.Error().Int64("incident", reminder.Incidentid).Err(err).Msg("record reminder")
+	}
+}
+
+func reminderMessage(reminder monitor_db.GetPendingRemindersRow, now time.Time) string {
+	return fmt.Sprintf(
+		"⏰ %s is still down for %s\nerror: %s\nreminder %d of %d",
+		reminder.Url,
+		now.Sub(reminder.Startedat).Round(time.Minute),
+		reminder.Error,
+		reminder.Reminders+1,
+		reminder.Maxreminders,
+	)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pafaul/telegram-http-monitor/monitor_db"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPendingReminders(t *testing.T) {
+	setupTestStorage(t)
+	ctx := context.Background()
+	endpoint := "https://api.com"
+	subscribe(t, 1, endpoint)
+	subscribe(t, 2, endpoint)
+
+	err := botStorage.q.SetReminder(ctx, monitor_db.SetReminderParams{
+		Reminderinterval: int64(30 * time.Minute / time.Second),
+		Maxreminders:     2,
+		Clientid:         sql.NullInt64{Int64: 1, Valid: true},
+		Url:              endpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alertedAt := time.Now().Add(-time.Hour)
+	incidentId, err := openIncident(ctx, botStorage.q, RequestError{
+		EndpointRequest: EndpointRequest{Endpoint: endpoint},
+		Error:           errors.New("timeout"),
+		DownSince:       alertedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chatId := range []int64{1, 2} {
+		err := botStorage.q.AddIncidentMessage(ctx, monitor_db.AddIncidentMessageParams{
+			Incidentid: incidentId,
+			Chatid:     chatId,
+			Messageid:  10 + chatId,
+			Notifiedat: sql.NullTime{Time: alertedAt, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reminders, err := botStorage.q.GetPendingReminders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reminders) != 1 || reminders[0].Chatid != 1 || reminders[0].Messageid != 11 {
+		t.Fatalf("expected reminder only for subscriber with interval, got %+v", reminders)
+	}
+	if !reminderDue(reminders[0], alertedAt.Add(30*time.Minute)) || reminderDue(reminders[0], alertedAt.Add(29*time.Minute)) {
+		t.Error("expected reminder to be due 30 minutes after the alert")
+	}
+
+	for i := 0; i < 2; i++ {
+		err := botStorage.q.RecordReminder(ctx, monitor_db.RecordReminderParams{
+			Notifiedat: sql.NullTime{Time: time.Now(), Valid: true},
+			Incidentid: incidentId,
+			Chatid:     1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reminders, _ := botStorage.q.GetPendingReminders(ctx); len(reminders) != 0 {
+		t.Errorf("expected no reminders after max count, got %+v", reminders)
+	}
+}
+
+func TestReminderMessage(t *testing.T) {
+	startedAt := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	message := reminderMessage(monitor_db.GetPendingRemindersRow{
+		Url:          "https://api.com",
+		Error:        "timeout",
+		Startedat:    startedAt,
+		Reminders:    1,
+		Maxreminders: 5,
+	}, startedAt.Add(time.Hour))
+
+	for _, expected := range []string{"still down for 1h0m0s", "error: timeout", "reminder 2 of 5"} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("expected %q in %q", expected, message)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+)
+
+var (
+	xpathComparison = regexp.MustCompile(`^(.+?)\s*(!=|<=|>=|=|<|>)\s*('[^']*'|"[^"]*"|[\d.]+)$`)
+)
+
+// checkResponseAssertions
+// Json responses are validated with json schema first.
+// Xpath is evaluated for xml responses, css selector for html responses.
+// Value they extract, or the whole body when neither is set,
+// must match expect pattern
+func checkResponseAssertions(r *EndpointRequest, body []byte) error {
+	if len(r.JsonSchema) > 0 {
+		if err := checkJsonSchema(r, body); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(r.XPath) > 0:
+		return checkXPath(body, r.XPath, r.Expect)
+	case len(r.Css) > 0:
+		return checkCssSelector(body, r.Css, r.Expect)
+	case len(r.Expect) > 0:
+		return matchExpected("response body", string(body), r.Expect)
+	}
+
+	return nil
+}
+
+// checkXPath
+// Boolean expressions like //status/text() = 'OK' must be true,
+// other expressions must select a value that matches expect pattern.
+// Failed comparison reports the value selected by its left side
+func checkXPath(body []byte, expression, expect string) error {
+	document, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("parse xml response: %w", err)
+	}
+
+	value, err := evaluateXPath(document, expression)
+	if err != nil {
+		return err
+	}
+
+	passed, isBoolean := value.(bool)
+	if !isBoolean {
+		return matchExpected("xpath "+expression, fmt.Sprint(value), expect)
+	}
+	if passed {
+		return nil
+	}
+
+	comparison := xpathComparison.FindStringSubmatch(expression)
+	if comparison == nil {
+		return fmt.Errorf("xpath %s is false", expression)
+	}
+
+	actual, err := evaluateXPath(document, comparison[1])
+	if err != nil {
+		return fmt.Errorf("xpath %s is false", expression)
+	}
+
+	return fmt.Errorf("xpath %s returned %q, expected %s %s", comparison[1], actual, comparison[2], comparison[3])
+}
+
+func evaluateXPath(document *xmlquery.Node, expression string) (any, error) {
+	compiled, err := xpath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath %s: %w", expression, err)
+	}
+
+	value := compiled.Evaluate(xmlquery.CreateXPathNavigator(document))
+	nodes, isNodeSet := value.(*xpath.NodeIterator)
+	if !isNodeSet {
+		return value, nil
+	}
+
+	if !nodes.MoveNext() {
+		return nil, fmt.Errorf("xpath %s selected nothing", expression)
+	}
+
+	return nodes.Current().Value(), nil
+}
+
+func checkCssSelector(body []byte, selector, expect string) error {
+	compiled, err := cascadia.Compile(selector)
+	if err != nil {
+		return fmt.Errorf("invalid css selector %s: %w", selector, err)
+	}
+
+	document, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("parse html response: %w", err)
+	}
+
+	element := cascadia.Query(document, compiled)
+	if element == nil {
+		return fmt.Errorf("css %s selected nothing", selector)
+	}
+
+	return matchExpected("css "+selector, strings.TrimSpace(nodeText(element)), expect)
+}
+
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+	}
+
+	return text.String()
+}
+
+func matchExpected(source, actual, expect string) error {
+	if len(expect) == 0 {
+		return nil
+	}
+
+	matched, err := regexp.MatchString(expect, actual)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return &AssertionError{Err: fmt.Errorf("%s returned %q, expected %s", source, truncate(actual, 64), expect)}
+	}
+
+	return nil
+}
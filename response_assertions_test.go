@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckXPath(t *testing.T) {
+	body := []byte(`<Envelope><Body><health><status>DEGRADED</status><version>2.1</version></health></Body></Envelope>`)
+
+	cases := []struct {
+		expression  string
+		expect      string
+		errContains string
+	}{
+		{expression: "//version/text()", expect: `^2\.`},
+		{expression: "//status/text() = 'DEGRADED'"},
+		{expression: "count(//health) = 1"},
+		{expression: "//status/text() = 'OK'", errContains: `returned "DEGRADED"`},
+		{expression: "//version", expect: `^3\.`, errContains: `returned "2.1"`},
+		{expression: "//missing", errContains: "selected nothing"},
+	}
+
+	for _, c := range cases {
+		err := checkResponseAssertions(&EndpointRequest{XPath: c.expression, Expect: c.expect}, body)
+		if len(c.errContains) == 0 && err != nil {
+			t.Errorf("%s: unexpected error %s", c.expression, err)
+		}
+		if len(c.errContains) > 0 && (err == nil || !strings.Contains(err.Error(), c.errContains)) {
+			t.Errorf("%s: expected error with %q, got %v", c.expression, c.errContains, err)
+		}
+	}
+}
+
+func TestCheckCssSelector(t *testing.T) {
+	body := []byte(`<html><body><footer><span id="build-version"> v1.9.3 </span></footer></body></html>`)
+
+	err := checkResponseAssertions(&EndpointRequest{Css: "#build-version"}, body)
+	if err != nil {
+		t.Errorf("expected element to exist, got %s", err)
+	}
+
+	err = checkResponseAssertions(&EndpointRequest{Css: "#build-version", Expect: `^v2\.`}, body)
+	if err == nil || !strings.Contains(err.Error(), `returned "v1.9.3"`) {
+		t.Errorf("expected actual version in error, got %v", err)
+	}
+
+	err = checkResponseAssertions(&EndpointRequest{Css: "#missing"}, body)
+	if err == nil {
+		t.Error("expected missing element to fail")
+	}
+}
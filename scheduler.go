@@ -0,0 +1,406 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"github.com/rs/zerolog/log"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	// Scheduler
+	// Keeps requests in a min-heap ordered by the next run time,
+	// so it sleeps until the earliest request is due.
+	//
+	// Every request runs in its own slot of the interval, derived from
+	// the endpoint hash, so requests with the same interval are spread
+	// over it and keep their slots after restart.
+	//
+	// Request lock can be held while taking the scheduler lock, never
+	// the other way round: check holds the request lock for its whole duration
+	Scheduler struct {
+		ReceiveChannel chan DueRequest
+		lock           sync.Mutex
+		queue          scheduleQueue
+		entries        map[string]*scheduledRequest
+		wakeup         chan struct{}
+		jitter         time.Duration
+	}
+
+	// scheduledRequest
+	// Slot is the run time without jitter,
+	// next slots are counted from it so jitter does not accumulate
+	scheduledRequest struct {
+		request *EndpointRequest
+		slot    time.Time
+		nextRun time.Time
+		index   int
+	}
+
+	// DueRequest
+	// DueAt is the run time of the request,
+	// delay until a worker starts the check is the scheduling lag
+	DueRequest struct {
+		Request *EndpointRequest
+		DueAt   time.Time
+	}
+
+	ScheduledCheck struct {
+		Endpoint string
+		RunAt    time.Time
+	}
+
+	scheduleQueue []*scheduledRequest
+)
+
+const (
+	defaultCheckInterval = time.Minute
+	minCheckInterval     = 10 * time.Second
+	maxCheckInterval     = 24 * time.Hour
+)
+
+func NewScheduler(numberOfElements int, jitter time.Duration) *Scheduler {
+	s := new(Scheduler)
+
+	s.jitter = jitter
+	s.ReceiveChannel = make(chan DueRequest)
+	s.queue = make(scheduleQueue, 0, numberOfElements)
+	s.entries = make(map[string]*scheduledRequest, numberOfElements)
+	s.wakeup = make(chan struct{}, 1)
+
+	return s
+}
+
+// Start
+// Scheduler must be started as a goroutine, due requests are sent
+// over a blocking channel one by one.
+//
+// Request is rescheduled before it is sent,
+// so a slow receiver delays the checks but never repeats them
+func (s *Scheduler) Start(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		now := time.Now()
+		request, wait := s.next(now)
+		if request != nil {
+			log.Debug().Str("endpoint", request.Endpoint).Msg("request is due")
+			select {
+			case <-ctx.Done():
+				return
+			case s.ReceiveChannel <- DueRequest{Request: request, DueAt: now.Add(wait)}:
+			}
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-timer.C:
+		}
+	}
+}
+
+// next
+// Returns the due request and moves it to its next run,
+// otherwise returns how long to wait for the earliest one.
+// For the due request the duration is not positive,
+// it is how late the request is returned
+func (s *Scheduler) next(now time.Time) (*EndpointRequest, time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, maxCheckInterval
+	}
+
+	earliest := s.queue[0]
+	wait := earliest.nextRun.Sub(now)
+	if wait > 0 {
+		return nil, wait
+	}
+
+	// missed slots are skipped when checks run late
+	earliest.slot = nextSlot(earliest.request, now)
+	earliest.nextRun = earliest.slot.Add(s.randomJitter())
+	heap.Fix(&s.queue, earliest.index)
+
+	return earliest.request, wait
+}
+
+func (s *Scheduler) randomJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+// nextSlot
+// Slots of the request are interval apart and shifted
+// from the unix epoch by the endpoint phase,
+// or follow the cron schedule of the request.
+// Returned slot is always after the given time
+func nextSlot(r *EndpointRequest, after time.Time) time.Time {
+	if r.cronSchedule != nil {
+		if slot := r.cronSchedule.Next(after); !slot.IsZero() {
+			return slot
+		}
+		return after.Add(maxCheckInterval)
+	}
+
+	interval := checkInterval(r)
+	offset := (after.UnixNano() - int64(schedulePhase(r.Endpoint, interval))) % int64(interval)
+	if offset < 0 {
+		offset += int64(interval)
+	}
+
+	return after.Add(interval - time.Duration(offset))
+}
+
+func schedulePhase(endpoint string, interval time.Duration) time.Duration {
+	hash := fnv.New64a()
+	hash.Write([]byte(endpoint))
+
+	return time.Duration(hash.Sum64() % uint64(interval))
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Add
+// Request runs in its next slot, or right away when runNow is set,
+// e.g. for a new endpoint that was never checked
+func (s *Scheduler) Add(request *EndpointRequest, runNow bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, exists := s.entries[request.Endpoint]; exists {
+		return
+	}
+
+	now := time.Now()
+	entry := &scheduledRequest{request: request, slot: nextSlot(request, now)}
+	entry.nextRun = entry.slot.Add(s.randomJitter())
+	if runNow {
+		entry.slot = now
+		entry.nextRun = now
+	}
+	s.entries[request.Endpoint] = entry
+	heap.Push(&s.queue, entry)
+	s.notify()
+}
+
+func (s *Scheduler) Remove(request *EndpointRequest) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry, exists := s.entries[request.Endpoint]
+	if !exists {
+		return false
+	}
+
+	heap.Remove(&s.queue, entry.index)
+	delete(s.entries, request.Endpoint)
+	s.notify()
+
+	return true
+}
+
+// Update
+// Replaces settings of the request with the same endpoint,
+// check state and credentials of the running request are kept.
+// Shorter interval brings the next run closer
+func (s *Scheduler) Update(request *EndpointRequest) bool {
+	for {
+		entry, current := s.lookup(request.Endpoint)
+		if entry == nil {
+			return false
+		}
+		if s.replace(entry, current, request) {
+			return true
+		}
+	}
+}
+
+// replace
+// Fails when the request was removed or replaced
+// while waiting for the check that holds its lock
+func (s *Scheduler) replace(entry *scheduledRequest, current, request *EndpointRequest) bool {
+	current.lock.Lock()
+	defer current.lock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.entries[request.Endpoint] != entry || entry.request != current {
+		return false
+	}
+
+	request.lock = current.lock
+	request.password = current.password
+	request.checkState = current.checkState
+	entry.request = request
+
+	if slot := nextSlot(request, time.Now()); slot.Before(entry.slot) {
+		entry.slot = slot
+		entry.nextRun = slot.Add(s.randomJitter())
+		heap.Fix(&s.queue, entry.index)
+		s.notify()
+	}
+
+	return true
+}
+
+// Get
+// Returns a copy of the request, so it can be checked
+// outside of the monitor schedule
+func (s *Scheduler) Get(endpoint string) (EndpointRequest, bool) {
+	_, current := s.lookup(endpoint)
+	if current == nil {
+		return EndpointRequest{}, false
+	}
+
+	current.lock.Lock()
+	defer current.lock.Unlock()
+
+	request := *current
+	request.lock = &sync.Mutex{}
+	request.checkState = checkState{}
+
+	return request, true
+}
+
+// lookup
+// Request is locked by the caller after the scheduler lock is released
+func (s *Scheduler) lookup(endpoint string) (*scheduledRequest, *EndpointRequest) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry, exists := s.entries[endpoint]
+	if !exists {
+		return nil, nil
+	}
+
+	return entry, entry.request
+}
+
+func (s *Scheduler) RequestError(endpoint string) error {
+	_, current := s.lookup(endpoint)
+	if current == nil {
+		return nil
+	}
+
+	current.lock.Lock()
+	defer current.lock.Unlock()
+
+	return current.requestError
+}
+
+// Reschedule
+// Moves the next run of the request, e.g. to confirm the failure
+// or to back off while the endpoint is down.
+// Run after it returns the request to its regular slots
+func (s *Scheduler) Reschedule(endpoint string, delay time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry, exists := s.entries[endpoint]
+	if !exists {
+		return
+	}
+
+	runAt := time.Now().Add(delay)
+	entry.slot = runAt
+	entry.nextRun = runAt
+	heap.Fix(&s.queue, entry.index)
+	s.notify()
+}
+
+// Preview
+// Lists upcoming checks of the endpoints in order of their run time,
+// later runs are shown without jitter
+func (s *Scheduler) Preview(endpoints []string, limit int) []ScheduledCheck {
+	s.lock.Lock()
+	preview := make(scheduleQueue, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if entry, exists := s.entries[endpoint]; exists {
+			preview = append(preview, &scheduledRequest{
+				request: entry.request,
+				slot:    entry.slot,
+				nextRun: entry.nextRun,
+			})
+		}
+	}
+	s.lock.Unlock()
+
+	heap.Init(&preview)
+	checks := make([]ScheduledCheck, 0, limit)
+	for len(checks) < limit && len(preview) > 0 {
+		earliest := preview[0]
+		checks = append(checks, ScheduledCheck{Endpoint: earliest.request.Endpoint, RunAt: earliest.nextRun})
+
+		earliest.slot = nextSlot(earliest.request, earliest.nextRun)
+		earliest.nextRun = earliest.slot
+		heap.Fix(&preview, 0)
+	}
+
+	return slices.Clip(checks)
+}
+
+func (s *Scheduler) RequestExists(request *EndpointRequest) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, exists := s.entries[request.Endpoint]
+	return exists
+}
+
+func checkInterval(r *EndpointRequest) time.Duration {
+	if r.Interval <= 0 {
+		return defaultCheckInterval
+	}
+
+	return r.Interval
+}
+
+func (q scheduleQueue) Len() int {
+	return len(q)
+}
+
+func (q scheduleQueue) Less(i, j int) bool {
+	return q[i].nextRun.Before(q[j].nextRun)
+}
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x any) {
+	entry := x.(*scheduledRequest)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *scheduleQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	entry.index = -1
+	return entry
+}
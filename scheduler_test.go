@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerNextRun(t *testing.T) {
+	s := NewScheduler(2, 0)
+	fast := &EndpointRequest{Endpoint: "https://fast.com", Interval: 10 * time.Second, lock: &sync.Mutex{}}
+	slow := &EndpointRequest{Endpoint: "https://slow.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	s.Add(fast, true)
+	s.Add(slow, true)
+
+	now := time.Now().Add(time.Millisecond)
+	due := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		request, _ := s.next(now)
+		if request == nil {
+			t.Fatal("expected new requests to be due right away")
+		}
+		due[request.Endpoint] = true
+	}
+	if len(due) != 2 {
+		t.Fatalf("expected both requests to run, got %v", due)
+	}
+
+	request, wait := s.next(now)
+	if request != nil || wait > 10*time.Second {
+		t.Fatalf("expected to wait for fast request, got %v after %s", request, wait)
+	}
+
+	request, _ = s.next(now.Add(wait))
+	if request != fast {
+		t.Fatalf("expected fast request to be due, got %v", request)
+	}
+
+	if !s.Remove(fast) {
+		t.Fatal("expected fast request to be removed")
+	}
+	if _, wait = s.next(now.Add(10 * time.Second)); wait <= 10*time.Second {
+		t.Fatalf("expected to wait for slow request, got %s", wait)
+	}
+}
+
+func TestSchedulerUpdateShortensInterval(t *testing.T) {
+	s := NewScheduler(1, 0)
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	s.Add(request, true)
+	s.next(time.Now().Add(time.Millisecond))
+
+	updated := &EndpointRequest{Endpoint: "https://api.com", Interval: 10 * time.Second}
+	if !s.Update(updated) {
+		t.Fatal("expected request to be updated")
+	}
+	if updated.lock != request.lock {
+		t.Error("expected lock of the running request to be kept")
+	}
+
+	if _, wait := s.next(time.Now()); wait > 10*time.Second {
+		t.Errorf("expected next run within new interval, got %s", wait)
+	}
+}
+
+func TestNextSlotKeepsPhase(t *testing.T) {
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Minute}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	slot := nextSlot(request, start)
+	if !slot.After(start) || slot.Sub(start) > time.Minute {
+		t.Fatalf("slot %s is not within interval after %s", slot, start)
+	}
+	if next := nextSlot(request, slot); next.Sub(slot) != time.Minute {
+		t.Errorf("expected slots to be interval apart, got %s", next.Sub(slot))
+	}
+	if restarted := nextSlot(request, slot.Add(-time.Second)); restarted != slot {
+		t.Errorf("expected slot to survive restart, got %s and %s", restarted, slot)
+	}
+}
+
+func TestSchedulerSpreadsSameInterval(t *testing.T) {
+	s := NewScheduler(100, 0)
+	for i := 0; i < 100; i++ {
+		s.Add(&EndpointRequest{
+			Endpoint: fmt.Sprintf("https://api.com/items/%d", i),
+			Interval: time.Minute,
+			lock:     &sync.Mutex{},
+		}, false)
+	}
+
+	seconds := map[int64]int{}
+	for _, check := range s.Preview(allScheduled(s), 100) {
+		seconds[check.RunAt.Unix()]++
+	}
+	for second, amount := range seconds {
+		if amount > 10 {
+			t.Errorf("%d checks are scheduled at %d", amount, second)
+		}
+	}
+	if len(seconds) < 30 {
+		t.Errorf("expected checks to be spread over the interval, got %d distinct seconds", len(seconds))
+	}
+}
+
+func TestSchedulerPreview(t *testing.T) {
+	s := NewScheduler(2, 0)
+	fast := &EndpointRequest{Endpoint: "https://fast.com", Interval: 10 * time.Second, lock: &sync.Mutex{}}
+	slow := &EndpointRequest{Endpoint: "https://slow.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	s.Add(fast, false)
+	s.Add(slow, false)
+
+	preview := s.Preview([]string{fast.Endpoint}, 3)
+	if len(preview) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(preview))
+	}
+	for i := 1; i < len(preview); i++ {
+		if preview[i].RunAt.Sub(preview[i-1].RunAt) != 10*time.Second {
+			t.Errorf("unexpected preview %v", preview)
+		}
+	}
+}
+
+func TestSchedulerStartIsIdleWhenEmpty(t *testing.T) {
+	s := NewScheduler(1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+
+	request := &EndpointRequest{Endpoint: "https://api.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	time.Sleep(10 * time.Millisecond)
+	s.Add(request, true)
+
+	select {
+	case received := <-s.ReceiveChannel:
+		if received.Request != request {
+			t.Fatalf("unexpected request %v", received.Request)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("added request was not scheduled")
+	}
+
+	select {
+	case received := <-s.ReceiveChannel:
+		t.Fatalf("request %s was scheduled before its interval", received.Request.Endpoint)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func allScheduled(s *Scheduler) []string {
+	endpoints := make([]string, 0, len(s.entries))
+	for endpoint := range s.entries {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+func TestSchedulerRunsWhileRequestIsChecked(t *testing.T) {
+	s := NewScheduler(2, 0)
+	checked := &EndpointRequest{Endpoint: "https://slow.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	other := &EndpointRequest{Endpoint: "https://other.com", Interval: time.Hour, lock: &sync.Mutex{}}
+	s.Add(checked, false)
+	s.Add(other, true)
+
+	// running check holds the request lock
+	checked.lock.Lock()
+	defer checked.lock.Unlock()
+	go s.Get(checked.Endpoint)
+	go s.Update(&EndpointRequest{Endpoint: checked.Endpoint, Interval: time.Minute})
+	time.Sleep(50 * time.Millisecond)
+
+	scheduled := make(chan *EndpointRequest)
+	go func() {
+		request, _ := s.next(time.Now().Add(time.Millisecond))
+		scheduled <- request
+	}()
+
+	select {
+	case request := <-scheduled:
+		if request != other {
+			t.Errorf("expected other request to be due, got %v", request)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("scheduler is blocked by the request that is checked")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type (
+	// SecretBox
+	// Encrypts monitor credentials with aes-gcm before they are stored in db,
+	// nonce is prepended to the sealed value
+	SecretBox struct {
+		aead cipher.AEAD
+	}
+)
+
+var (
+	ErrSecretsDisabled = errors.New("secret key is not configured")
+	ErrInvalidSecret   = errors.New("secret is too short")
+	ErrSecretMismatch  = errors.New("endpoint is monitored with other credentials")
+)
+
+// NewSecretBox
+// Key is base64 encoded 16, 24 or 32 bytes, e.g. `openssl rand -base64 32`.
+// Empty key returns a box that refuses to seal or open secrets
+func NewSecretBox(key string) (*SecretBox, error) {
+	box := new(SecretBox)
+	if len(key) == 0 {
+		return box, nil
+	}
+
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decode secret key: %w", err)
+	}
+
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	box.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return box, nil
+}
+
+func (b *SecretBox) Seal(secret string) ([]byte, error) {
+	if b.aead == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(secret)+b.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+func (b *SecretBox) Open(sealed []byte) (string, error) {
+	if b.aead == nil {
+		return "", ErrSecretsDisabled
+	}
+
+	if len(sealed) < b.aead.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	secret, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
@@ -0,0 +1 @@
+ALTER TABLE urls_to_request DROP COLUMN settings;
@@ -0,0 +1 @@
+ALTER TABLE urls_to_request ADD COLUMN settings TEXT NOT NULL DEFAULT '';
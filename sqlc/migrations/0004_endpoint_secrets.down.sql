@@ -0,0 +1 @@
+DROP TABLE endpoint_secrets;
@@ -0,0 +1,5 @@
+CREATE TABLE endpoint_secrets(
+    urlId INTEGER PRIMARY KEY,
+    secret BLOB NOT NULL,
+    FOREIGN KEY (urlId) REFERENCES urls_to_request(id) on delete cascade
+);
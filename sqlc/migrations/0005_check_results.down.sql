@@ -0,0 +1,2 @@
+DROP INDEX check_results_url_checked_at;
+DROP TABLE check_results;
@@ -0,0 +1,15 @@
+CREATE TABLE check_results(
+    id INTEGER PRIMARY KEY,
+    urlId INTEGER NOT NULL,
+    checkedAt TIMESTAMP NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    dnsMs INTEGER NOT NULL DEFAULT 0,
+    connectMs INTEGER NOT NULL DEFAULT 0,
+    tlsMs INTEGER NOT NULL DEFAULT 0,
+    firstByteMs INTEGER NOT NULL DEFAULT 0,
+    transferMs INTEGER NOT NULL DEFAULT 0,
+    totalMs INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (urlId) REFERENCES urls_to_request(id) on delete cascade
+);
+
+CREATE INDEX check_results_url_checked_at ON check_results(urlId, checkedAt);
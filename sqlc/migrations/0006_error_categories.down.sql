@@ -0,0 +1,2 @@
+ALTER TABLE user_url_subscription DROP COLUMN categories;
+ALTER TABLE check_results DROP COLUMN category;
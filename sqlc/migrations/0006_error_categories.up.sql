@@ -0,0 +1,2 @@
+ALTER TABLE check_results ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_url_subscription ADD COLUMN categories TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE maintenance_windows(
+    id INTEGER PRIMARY KEY,
+    clientId INTEGER NOT NULL,
+    target TEXT NOT NULL,
+    startsAt TIMESTAMP NOT NULL,
+    durationSeconds INTEGER NOT NULL,
+    recurrence TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    FOREIGN KEY (clientId) REFERENCES clients(clientId) on delete cascade
+);
@@ -0,0 +1 @@
+ALTER TABLE user_url_subscription DROP COLUMN paused;
@@ -0,0 +1 @@
+ALTER TABLE user_url_subscription ADD COLUMN paused BOOLEAN NOT NULL DEFAULT false;
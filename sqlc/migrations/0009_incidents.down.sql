@@ -0,0 +1,2 @@
+DROP TABLE incident_messages;
+DROP TABLE incidents;
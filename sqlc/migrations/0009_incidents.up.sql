@@ -0,0 +1,18 @@
+CREATE TABLE incidents(
+    id INTEGER PRIMARY KEY,
+    urlId INTEGER NOT NULL,
+    startedAt TIMESTAMP NOT NULL,
+    resolvedAt TIMESTAMP,
+    error TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (urlId) REFERENCES urls_to_request(id) on delete cascade
+);
+
+CREATE INDEX incidents_url ON incidents(urlId, resolvedAt);
+
+CREATE TABLE incident_messages(
+    incidentId INTEGER NOT NULL,
+    chatId INTEGER NOT NULL,
+    messageId INTEGER NOT NULL,
+    PRIMARY KEY (incidentId, chatId),
+    FOREIGN KEY (incidentId) REFERENCES incidents(id) on delete cascade
+);
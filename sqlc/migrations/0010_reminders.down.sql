@@ -0,0 +1,4 @@
+ALTER TABLE incident_messages DROP COLUMN notifiedat;
+ALTER TABLE incident_messages DROP COLUMN reminders;
+ALTER TABLE user_url_subscription DROP COLUMN maxreminders;
+ALTER TABLE user_url_subscription DROP COLUMN reminderinterval;
@@ -0,0 +1,4 @@
+ALTER TABLE user_url_subscription ADD COLUMN reminderinterval INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_url_subscription ADD COLUMN maxreminders INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE incident_messages ADD COLUMN reminders INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE incident_messages ADD COLUMN notifiedat TIMESTAMP;
@@ -0,0 +1,5 @@
+ALTER TABLE incident_messages DROP COLUMN muted;
+ALTER TABLE incident_messages DROP COLUMN snoozeduntil;
+ALTER TABLE incidents DROP COLUMN ackedat;
+ALTER TABLE incidents DROP COLUMN ackedbyid;
+ALTER TABLE incidents DROP COLUMN ackedby;
@@ -0,0 +1,5 @@
+ALTER TABLE incidents ADD COLUMN ackedby TEXT NOT NULL DEFAULT '';
+ALTER TABLE incidents ADD COLUMN ackedbyid INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE incidents ADD COLUMN ackedat TIMESTAMP;
+ALTER TABLE incident_messages ADD COLUMN snoozeduntil TIMESTAMP;
+ALTER TABLE incident_messages ADD COLUMN muted BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP TABLE incident_escalations;
+DROP TABLE escalation_steps;
+DROP TABLE escalation_policies;
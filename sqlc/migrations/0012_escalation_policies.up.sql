@@ -0,0 +1,27 @@
+CREATE TABLE escalation_policies(
+    id INTEGER PRIMARY KEY,
+    clientId INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    target TEXT NOT NULL,
+    UNIQUE (clientId, name),
+    FOREIGN KEY (clientId) REFERENCES clients(clientId) on delete cascade
+);
+
+CREATE TABLE escalation_steps(
+    policyId INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    chatId INTEGER NOT NULL,
+    delaySeconds INTEGER NOT NULL,
+    PRIMARY KEY (policyId, position),
+    FOREIGN KEY (policyId) REFERENCES escalation_policies(id) on delete cascade
+);
+
+CREATE TABLE incident_escalations(
+    incidentId INTEGER NOT NULL,
+    policyId INTEGER NOT NULL,
+    step INTEGER NOT NULL DEFAULT 0,
+    escalateAt TIMESTAMP NOT NULL,
+    PRIMARY KEY (incidentId, policyId),
+    FOREIGN KEY (incidentId) REFERENCES incidents(id) on delete cascade,
+    FOREIGN KEY (policyId) REFERENCES escalation_policies(id) on delete cascade
+);
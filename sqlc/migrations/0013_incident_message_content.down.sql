@@ -0,0 +1,2 @@
+ALTER TABLE incident_messages DROP COLUMN document;
+ALTER TABLE incident_messages DROP COLUMN content;
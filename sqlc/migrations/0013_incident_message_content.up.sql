@@ -0,0 +1,2 @@
+ALTER TABLE incident_messages ADD COLUMN content TEXT NOT NULL DEFAULT '';
+ALTER TABLE incident_messages ADD COLUMN document BOOLEAN NOT NULL DEFAULT false;
@@ -8,20 +8,35 @@ delete from clients where clientId = ?;
 select id from urls_to_request where url = ?;
 
 -- name: AddUrlToTrack :one
-insert into urls_to_request(url) values (?) returning id;
+insert into urls_to_request(url, settings) values (?, ?) returning id;
 
 -- name: RemoveUrlToTrack :exec
 delete from urls_to_request where url = ?;
 
 -- name: GetEndpointsToMonitor :many
-select * from urls_to_request;
+select ur.id, ur.url, ur.settings, es.secret
+from urls_to_request ur
+left join endpoint_secrets es on es.urlId = ur.id
+where exists (
+    select 1 from user_url_subscription uus where uus.urlId = ur.id and not uus.paused
+);
+
+-- name: GetEndpointToMonitor :one
+select ur.id, ur.url, ur.settings, es.secret
+from urls_to_request ur
+left join endpoint_secrets es on es.urlId = ur.id
+where ur.url = ?;
+
+-- name: SetEndpointSecret :exec
+insert into endpoint_secrets(urlId, secret) values (?, ?)
+on conflict(urlId) do update set secret = excluded.secret;
 
 -- name: GetUsersToNotify :many
-select c.clientId
+select c.clientId, uus.categories
 from clients c
 inner join user_url_subscription uus on c.clientId = uus.clientId
 inner join urls_to_request ur on uus.urlId = ur.id
-where ur.url = ?;
+where ur.url = ? and not uus.paused;
 
 -- name: GetUserMonitoredEndpoints :many
 select ur.url
@@ -31,8 +46,226 @@ inner join clients c on uus.clientId = c.clientId
 where c.clientId = ?
 order by uus.id;
 
+-- name: GetUserSubscriptions :many
+select ur.url, uus.paused
+from urls_to_request ur
+inner join user_url_subscription uus on ur.id = uus.urlId
+where uus.clientId = ?
+order by uus.id;
+
+-- name: SetSubscriptionPaused :exec
+update user_url_subscription set paused = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?);
+
+-- name: CountActiveSubscriptions :one
+select count(*)
+from user_url_subscription uus
+inner join urls_to_request ur on uus.urlId = ur.id
+where ur.url = ? and not uus.paused;
+
 -- name: AddSubscription :exec
 insert into user_url_subscription (clientId, urlId) values (?, ?);
 
 -- name: RemoveSubscription :exec
-delete from user_url_subscription where clientId = ? and urlId = ?;
\ No newline at end of file
+delete from user_url_subscription where clientId = ? and urlId = ?;
+
+-- name: CountUrlSubscriptions :one
+select count(*) from user_url_subscription where urlId = ?;
+
+-- name: GetEndpointSecret :one
+select secret from endpoint_secrets where urlId = ?;
+
+-- name: RemoveEndpointSecret :exec
+delete from endpoint_secrets where urlId = ?;
+
+-- name: RemoveCheckResults :exec
+delete from check_results where urlId = ?;
+
+-- name: GetEndpointSettings :one
+select settings from urls_to_request where url = ?;
+
+-- name: UpdateEndpointSettings :exec
+update urls_to_request set settings = ? where url = ?;
+
+-- name: AddCheckResult :exec
+insert into check_results(urlId, checkedAt, error, category, dnsMs, connectMs, tlsMs, firstByteMs, transferMs, totalMs)
+select id, ?, ?, ?, ?, ?, ?, ?, ?, ? from urls_to_request where url = ?;
+
+-- name: GetCheckHistory :many
+select cr.checkedAt, cr.error, cr.category, cr.dnsMs, cr.connectMs, cr.tlsMs, cr.firstByteMs, cr.transferMs, cr.totalMs
+from check_results cr
+inner join urls_to_request ur on cr.urlId = ur.id
+where ur.url = ?
+order by cr.checkedAt desc
+limit ?;
+
+-- name: RemoveCheckResultsBefore :exec
+delete from check_results where checkedAt < ?;
+
+-- name: SetAlertCategories :exec
+update user_url_subscription set categories = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?);
+
+-- name: AddMaintenanceWindow :one
+insert into maintenance_windows(clientId, target, startsAt, durationSeconds, recurrence, timezone)
+values (?, ?, ?, ?, ?, ?) returning id;
+
+-- name: GetClientMaintenanceWindows :many
+select * from maintenance_windows where clientId = ? order by startsAt;
+
+-- name: GetMaintenanceWindows :many
+select * from maintenance_windows;
+
+-- name: RemoveMaintenanceWindow :execrows
+delete from maintenance_windows where id = ? and clientId = ?;
+
+-- name: OpenIncident :one
+insert into incidents(urlId, startedAt, error)
+select id, ?, ? from urls_to_request where url = ?
+returning id;
+
+-- name: GetOpenIncident :one
+select i.id, i.startedAt, i.error
+from incidents i
+inner join urls_to_request ur on i.urlId = ur.id
+where ur.url = ? and i.resolvedAt is null
+order by i.id desc
+limit 1;
+
+-- name: GetOpenIncidents :many
+select ur.url, i.startedAt, i.error, (
+    select count(*) from check_results cr
+    where cr.urlId = i.urlId and cr.checkedAt >= i.startedAt and cr.error != ''
+) as failedChecks
+from incidents i
+inner join urls_to_request ur on i.urlId = ur.id
+where i.resolvedAt is null;
+
+-- name: ResolveIncident :exec
+update incidents set resolvedAt = ? where id = ?;
+
+-- name: AddIncidentMessage :exec
+insert or replace into incident_messages(incidentId, chatId, messageId, notifiedat, content, document)
+values (?, ?, ?, ?, ?, ?);
+
+-- name: AddEscalationMessage :exec
+insert into incident_messages(incidentId, chatId, messageId, notifiedat, content, document)
+values (?, ?, ?, ?, ?, ?)
+on conflict (incidentId, chatId) do nothing;
+
+-- name: CountChatIncidentMessages :one
+select count(*) from incident_messages where incidentId = ? and chatId = ?;
+
+-- name: GetIncidentMessages :many
+select chatId, messageId, content, document from incident_messages where incidentId = ?;
+
+-- name: RemoveIncidents :exec
+delete from incidents where urlId = ?;
+
+-- name: RemoveIncidentMessages :exec
+delete from incident_messages where incidentId in (select id from incidents where urlId = ?);
+
+-- name: RemoveIncidentsResolvedBefore :exec
+delete from incidents where resolvedAt < ?;
+
+-- name: RemoveIncidentMessagesResolvedBefore :exec
+delete from incident_messages where incidentId in (select id from incidents where resolvedAt < ?);
+
+-- name: SetReminder :exec
+update user_url_subscription set reminderinterval = ?, maxreminders = ?
+where clientId = ? and urlId = (select id from urls_to_request where url = ?);
+
+-- name: GetPendingReminders :many
+select im.incidentId, im.chatId, im.messageId, im.reminders, im.notifiedat, im.snoozeduntil,
+       i.startedAt, i.error, ur.url, uus.reminderinterval, uus.maxreminders
+from incident_messages im
+inner join incidents i on im.incidentId = i.id
+inner join urls_to_request ur on i.urlId = ur.id
+inner join user_url_subscription uus on uus.urlId = i.urlId and uus.clientId = im.chatId
+where i.resolvedAt is null
+  and i.ackedat is null
+  and uus.reminderinterval > 0
+  and im.reminders < uus.maxreminders
+  and not im.muted
+  and not uus.paused;
+
+-- name: RecordReminder :exec
+update incident_messages set reminders = reminders + 1, notifiedat = ?
+where incidentId = ? and chatId = ?;
+
+-- name: AcknowledgeIncident :execrows
+update incidents set ackedby = ?, ackedbyid = ?, ackedat = ?
+where id = ? and resolvedAt is null and ackedat is null;
+
+-- name: SnoozeIncidentMessage :execrows
+update incident_messages set snoozeduntil = ?
+where incidentId = ? and chatId = ?;
+
+-- name: MuteIncidentMessage :execrows
+update incident_messages set muted = true
+where incidentId = ? and chatId = ?;
+
+-- name: AddEscalationPolicy :one
+insert into escalation_policies(clientId, name, target) values (?, ?, ?)
+on conflict (clientId, name) do update set target = excluded.target
+returning id;
+
+-- name: AddEscalationStep :exec
+insert into escalation_steps(policyId, position, chatId, delaySeconds) values (?, ?, ?, ?);
+
+-- name: RemoveEscalationSteps :exec
+delete from escalation_steps where policyId = ?;
+
+-- name: GetEscalationPolicyId :one
+select id from escalation_policies where clientId = ? and name = ?;
+
+-- name: RemoveEscalationPolicy :exec
+delete from escalation_policies where id = ?;
+
+-- name: RemovePolicyEscalations :exec
+delete from incident_escalations where policyId = ?;
+
+-- name: GetClientEscalationPolicies :many
+select ep.id, ep.name, ep.target, es.chatId, es.delaySeconds
+from escalation_policies ep
+inner join escalation_steps es on es.policyId = ep.id
+where ep.clientId = ?
+order by ep.name, es.position;
+
+-- name: GetEndpointEscalationPolicies :many
+select ep.id, ep.clientId, ep.target
+from escalation_policies ep
+where ep.clientId in (
+    select uus.clientId
+    from user_url_subscription uus
+    inner join urls_to_request ur on uus.urlId = ur.id
+    where ur.url = ? and not uus.paused
+);
+
+-- name: GetEscalationSteps :many
+select chatId, delaySeconds from escalation_steps where policyId = ? order by position;
+
+-- name: StartEscalation :exec
+insert or ignore into incident_escalations(incidentId, policyId, step, escalateAt) values (?, ?, 0, ?);
+
+-- name: GetEscalations :many
+select ie.incidentId, ie.policyId, ie.step, ie.escalateAt, ep.name,
+       i.startedAt, i.error, i.ackedat, i.resolvedAt, ur.url
+from incident_escalations ie
+inner join incidents i on ie.incidentId = i.id
+inner join urls_to_request ur on i.urlId = ur.id
+inner join escalation_policies ep on ie.policyId = ep.id
+where exists (
+    select 1 from user_url_subscription uus
+    where uus.urlId = i.urlId and uus.clientId = ep.clientId and not uus.paused
+);
+
+-- name: AdvanceEscalation :exec
+update incident_escalations set step = ?, escalateAt = ?
+where incidentId = ? and policyId = ?;
+
+-- name: StopEscalation :exec
+delete from incident_escalations where incidentId = ? and policyId = ?;
+
+-- name: RemoveIncidentEscalations :exec
+delete from incident_escalations where incidentId in (select id from incidents where urlId = ?);
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"unicode/utf8"
+)
+
+// truncate
+// Cuts the string to at most length bytes without splitting a rune,
+// telegram rejects messages that are not valid utf-8
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	for length > 0 && !utf8.RuneStart(s[length]) {
+		length--
+	}
+
+	return s[:length] + "..."
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	udpPresetDns = "dns"
+
+	defaultDnsName = "example.com"
+	defaultDnsType = "A"
+
+	dnsHeaderLength = 12
+	dnsClassIN      = 1
+)
+
+var (
+	ErrUnknownPreset = errors.New("unknown udp preset")
+
+	dnsTypes = map[string]uint16{
+		"A":     1,
+		"NS":    2,
+		"CNAME": 5,
+		"SOA":   6,
+		"MX":    15,
+		"TXT":   16,
+		"AAAA":  28,
+		"SRV":   33,
+	}
+
+	dnsResponseCodes = map[uint16]string{
+		1: "FORMERR",
+		2: "SERVFAIL",
+		3: "NXDOMAIN",
+		4: "NOTIMP",
+		5: "REFUSED",
+	}
+)
+
+// checkUdp
+// Sends the configured payload and waits for a single response datagram.
+// Any response is accepted unless expect pattern is set,
+// dns preset replaces the payload with a real query and validates the answer
+func checkUdp(address string, r *EndpointRequest, timeout time.Duration) error {
+	payload, err := udpPayload(r)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+
+	response := make([]byte, 64*1024)
+	n, err := conn.Read(response)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("no response from %s within %s: %w", address, timeout, os.ErrDeadlineExceeded)
+	}
+	if err != nil {
+		return err
+	}
+	response = response[:n]
+
+	if r.Preset == udpPresetDns {
+		if err := checkDnsResponse(payload, response); err != nil {
+			return err
+		}
+	}
+
+	return matchExpected("response", string(response), r.Expect)
+}
+
+func udpPayload(r *EndpointRequest) ([]byte, error) {
+	switch {
+	case r.Preset == udpPresetDns:
+		dnsName, dnsType := r.DnsName, strings.ToUpper(r.DnsType)
+		if len(dnsName) == 0 {
+			dnsName = defaultDnsName
+		}
+		if len(dnsType) == 0 {
+			dnsType = defaultDnsType
+		}
+		return buildDnsQuery(uint16(rand.Uint32()), dnsName, dnsType)
+	case len(r.Preset) > 0:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPreset, r.Preset)
+	case len(r.PayloadHex) > 0:
+		return hex.DecodeString(r.PayloadHex)
+	}
+
+	return []byte(r.Payload), nil
+}
+
+func buildDnsQuery(id uint16, name, queryType string) ([]byte, error) {
+	typeCode, ok := dnsTypes[queryType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dns query type %s", queryType)
+	}
+
+	query := make([]byte, dnsHeaderLength, dnsHeaderLength+len(name)+6)
+	binary.BigEndian.PutUint16(query[0:2], id)
+	// recursion desired
+	binary.BigEndian.PutUint16(query[2:4], 0x0100)
+	binary.BigEndian.PutUint16(query[4:6], 1)
+
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		if len(label) == 0 {
+			continue
+		}
+		if len(label) > 63 {
+			return nil, fmt.Errorf("dns label %s is longer than 63 characters", label)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0)
+	query = binary.BigEndian.AppendUint16(query, typeCode)
+	query = binary.BigEndian.AppendUint16(query, dnsClassIN)
+
+	return query, nil
+}
+
+// checkDnsResponse
+// Resolver is considered healthy when it answers the query
+// with NOERROR and at least one record
+func checkDnsResponse(query, response []byte) error {
+	if len(response) < dnsHeaderLength {
+		return fmt.Errorf("dns response is too short: %d bytes", len(response))
+	}
+
+	if binary.BigEndian.Uint16(response[0:2]) != binary.BigEndian.Uint16(query[0:2]) {
+		return errors.New("dns response id does not match the query")
+	}
+
+	flags := binary.BigEndian.Uint16(response[2:4])
+	if flags&0x8000 == 0 {
+		return errors.New("dns server replied with a query instead of a response")
+	}
+
+	if responseCode := flags & 0x000f; responseCode != 0 {
+		codeName, ok := dnsResponseCodes[responseCode]
+		if !ok {
+			codeName = fmt.Sprintf("RCODE %d", responseCode)
+		}
+		return fmt.Errorf("dns server answered with %s", codeName)
+	}
+
+	if binary.BigEndian.Uint16(response[6:8]) == 0 {
+		return errors.New("dns server answered without records")
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func startUdpServer(t *testing.T, reply func(request []byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := reply(buffer[:n]); response != nil {
+				conn.WriteTo(response, address)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestCheckUdpExpect(t *testing.T) {
+	address := startUdpServer(t, func(request []byte) []byte {
+		return append([]byte("pong "), request...)
+	})
+
+	request := &EndpointRequest{Endpoint: "udp://" + address, Payload: "ping", Expect: "^pong ping$"}
+	if err := checkUdp(address, request, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	request.Expect = "^pang"
+	if err := checkUdp(address, request, time.Second); err == nil {
+		t.Error("expected pattern mismatch")
+	}
+}
+
+func TestCheckUdpNoResponse(t *testing.T) {
+	address := startUdpServer(t, func(_ []byte) []byte { return nil })
+
+	request := &EndpointRequest{Endpoint: "udp://" + address, PayloadHex: "00ff"}
+	if err := checkUdp(address, request, 100*time.Millisecond); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestCheckUdpDnsPreset(t *testing.T) {
+	cases := []struct {
+		responseCode uint16
+		answers      uint16
+		expectError  bool
+	}{
+		{responseCode: 0, answers: 1, expectError: false},
+		{responseCode: 2, answers: 0, expectError: true},
+		{responseCode: 0, answers: 0, expectError: true},
+	}
+
+	for _, c := range cases {
+		c := c
+		address := startUdpServer(t, func(query []byte) []byte {
+			response := append([]byte{}, query...)
+			binary.BigEndian.PutUint16(response[2:4], 0x8180|c.responseCode)
+			binary.BigEndian.PutUint16(response[6:8], c.answers)
+			return response
+		})
+
+		request := &EndpointRequest{Endpoint: "udp://" + address, Preset: udpPresetDns, DnsName: "example.com"}
+		err := checkUdp(address, request, time.Second)
+		if (err != nil) != c.expectError {
+			t.Errorf("rcode %d with %d answers: unexpected result %v", c.responseCode, c.answers, err)
+		}
+	}
+}
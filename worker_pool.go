@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// WorkerPool
+	// Runs workers that can be added or stopped while the monitor works,
+	// stopped worker finishes its current check before it exits
+	WorkerPool struct {
+		lock    sync.Mutex
+		ctx     context.Context
+		work    func(ctx context.Context, workerId int, stop <-chan struct{})
+		stops   []chan struct{}
+		size    int
+		nextId  int
+		started bool
+		wg      sync.WaitGroup
+	}
+
+	// LagStats
+	// Collects scheduling lag and busy time of the workers
+	// between two evaluations of the pool
+	LagStats struct {
+		lock   sync.Mutex
+		since  time.Time
+		checks int
+		late   int
+		maxLag time.Duration
+		busy   time.Duration
+	}
+
+	LagReport struct {
+		Period      time.Duration
+		Workers     int
+		Checks      int
+		Late        int
+		MaxLag      time.Duration
+		Utilization float64
+	}
+)
+
+const (
+	// check is late when it starts this much after its due time
+	lateCheckLag          = time.Second
+	defaultLagAlert       = time.Minute
+	lagAlertCooldown      = 15 * time.Minute
+	poolEvaluateInterval  = 30 * time.Second
+	poolScaleDownIdleness = 0.5
+)
+
+func NewWorkerPool(size int, work func(ctx context.Context, workerId int, stop <-chan struct{})) *WorkerPool {
+	pool := new(WorkerPool)
+
+	pool.size = size
+	pool.work = work
+
+	return pool
+}
+
+// Start
+// Starts the workers of the current size,
+// later resizes are applied right away
+func (p *WorkerPool) Start(ctx context.Context) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ctx = ctx
+	p.started = true
+	p.resize()
+}
+
+// Resize
+// Size below one is raised to one, so checks never stop
+func (p *WorkerPool) Resize(size int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.size = max(size, 1)
+	if p.started {
+		p.resize()
+	}
+}
+
+func (p *WorkerPool) resize() {
+	for len(p.stops) < p.size {
+		stop := make(chan struct{})
+		p.stops = append(p.stops, stop)
+
+		p.wg.Add(1)
+		go func(id int) {
+			defer p.wg.Done()
+			p.work(p.ctx, id, stop)
+		}(p.nextId)
+		p.nextId++
+	}
+
+	for len(p.stops) > p.size {
+		close(p.stops[len(p.stops)-1])
+		p.stops = p.stops[:len(p.stops)-1]
+	}
+}
+
+func (p *WorkerPool) Size() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.size
+}
+
+// Wait
+// Blocks until all workers have exited, after the context is done
+func (p *WorkerPool) Wait() {
+	p.wg.Wait()
+}
+
+func NewLagStats(now time.Time) *LagStats {
+	return &LagStats{since: now}
+}
+
+func (s *LagStats) Record(lag, busy time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.checks++
+	s.busy += busy
+	if lag >= lateCheckLag {
+		s.late++
+	}
+	s.maxLag = max(s.maxLag, lag)
+}
+
+// Take
+// Returns report of the period since the previous Take and starts a new one
+func (s *LagStats) Take(now time.Time, workers int) LagReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	report := LagReport{
+		Period:  now.Sub(s.since),
+		Workers: workers,
+		Checks:  s.checks,
+		Late:    s.late,
+		MaxLag:  s.maxLag,
+	}
+	if capacity := time.Duration(workers) * report.Period; capacity > 0 {
+		report.Utilization = float64(s.busy) / float64(capacity)
+	}
+
+	s.since = now
+	s.checks, s.late = 0, 0
+	s.maxLag, s.busy = 0, 0
+
+	return report
+}
+
+func (r LagReport) String() string {
+	return fmt.Sprintf(
+		"workers: %d, utilization: %.0f%%, checks: %d, late: %d, max lag: %s",
+		r.Workers,
+		r.Utilization*100,
+		r.Checks,
+		r.Late,
+		r.MaxLag.Round(time.Millisecond),
+	)
+}
+
+// autoscaleSize
+// Pool grows by half while checks run late and shrinks
+// by one worker when checks are on time and workers are mostly idle
+func autoscaleSize(report LagReport, minWorkers, maxWorkers int) int {
+	size := report.Workers
+
+	switch {
+	case report.MaxLag >= lateCheckLag:
+		size += (size + 1) / 2
+	case report.Utilization < poolScaleDownIdleness:
+		size--
+	}
+
+	return min(max(size, minWorkers), maxWorkers)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolResize(t *testing.T) {
+	var running atomic.Int32
+	pool := NewWorkerPool(2, func(ctx context.Context, _ int, stop <-chan struct{}) {
+		running.Add(1)
+		defer running.Add(-1)
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+
+	expectRunning := func(expected int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for running.Load() != expected && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if running.Load() != expected {
+			t.Fatalf("expected %d workers, got %d", expected, running.Load())
+		}
+	}
+
+	expectRunning(2)
+	pool.Resize(5)
+	expectRunning(5)
+	pool.Resize(0)
+	expectRunning(1)
+
+	cancel()
+	pool.Wait()
+	expectRunning(0)
+}
+
+func TestLagStatsTake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewLagStats(start)
+	stats.Record(10*time.Millisecond, 5*time.Second)
+	stats.Record(3*time.Second, 10*time.Second)
+
+	report := stats.Take(start.Add(30*time.Second), 2)
+	if report.Checks != 2 || report.Late != 1 || report.MaxLag != 3*time.Second {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Utilization != 0.25 {
+		t.Errorf("expected utilization 0.25, got %f", report.Utilization)
+	}
+
+	if next := stats.Take(start.Add(time.Minute), 2); next.Checks != 0 || next.Period != 30*time.Second {
+		t.Errorf("expected stats to be reset, got %+v", next)
+	}
+}
+
+func TestAutoscaleSize(t *testing.T) {
+	cases := []struct {
+		report   LagReport
+		expected int
+	}{
+		{LagReport{Workers: 4, MaxLag: 5 * time.Second, Utilization: 1}, 6},
+		{LagReport{Workers: 7, MaxLag: 5 * time.Second, Utilization: 1}, 8},
+		{LagReport{Workers: 4, MaxLag: 100 * time.Millisecond, Utilization: 0.2}, 3},
+		{LagReport{Workers: 2, MaxLag: 0, Utilization: 0.1}, 2},
+		{LagReport{Workers: 4, MaxLag: 100 * time.Millisecond, Utilization: 0.8}, 4},
+	}
+
+	for _, c := range cases {
+		if size := autoscaleSize(c.report, 2, 8); size != c.expected {
+			t.Errorf("expected %d workers for %+v, got %d", c.expected, c.report, size)
+		}
+	}
+}